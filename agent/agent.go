@@ -1,17 +1,34 @@
 package agent
 
 import (
+	"context"
 	"fmt"
+	"github.com/cbwfree/micro-game/app"
 	"github.com/cbwfree/micro-game/codec"
+	"github.com/cbwfree/micro-game/meta"
 	"github.com/cbwfree/micro-game/utils/color"
 	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/cbwfree/micro-game/utils/metrics"
+	"github.com/cbwfree/micro-game/utils/pb"
+	"github.com/golang/protobuf/proto"
+	"io"
+	"strconv"
 	"sync"
+	"time"
 )
 
 var (
 	RegisterAgent = map[string]func(agent *Agent) Server{}
 )
 
+// 网关健康检查名称 (注册名称为 agent:监听地址, 通过所属服务注册时附加服务节点)
+const HealthCheckName = "agent"
+
+// 握手协议号 (客户端连接后可选的首个消息, 数据为 pb.Handshake, 用于协商消息头版本及序列化方式)
+// 	握手消息及其响应固定使用旧版消息头, 未握手的连接使用旧版消息头
+const CmdHandshake uint32 = 0
+
 type Agent struct {
 	sync.RWMutex
 	wg          *sync.WaitGroup
@@ -20,8 +37,12 @@ type Agent struct {
 	clientCodec *codec.Client
 	serverCodec *codec.Server
 	clients     map[string]Client
+	closed      bool
+	exit        chan struct{}
+	healthSrv   *app.Service // 注册健康检查的服务
+	healthName  string       // 健康检查名称
 
-	OnReceive    func(Client, *codec.ClientHead, []byte) (*codec.ServerHead, []byte, error) // 收到数据调用
+	OnReceive    func(Client, *codec.ClientHead, []byte) (*codec.ServerHead, []byte, error) // 收到数据调用 (数据仅在调用期间有效)
 	OnDisconnect func(Client)                                                               // 连接断开时调用
 }
 
@@ -46,6 +67,38 @@ func (g *Agent) ServerCodec() *codec.Server {
 	return g.serverCodec
 }
 
+// NewMeta 创建客户端Meta (网关节点为所属服务)
+func (g *Agent) NewMeta(clientId string) *Meta {
+	return newMeta(g.service(), clientId)
+}
+
+// NewDecoder 创建客户端消息解码器 (使用旧版消息头, 握手协商后切换版本)
+func (g *Agent) NewDecoder(r io.Reader) *codec.Decoder {
+	dec := codec.NewDecoder(r, g.clientCodec)
+	dec.SetVersion(codec.Version1)
+	return dec
+}
+
+// Decode 读取客户端消息 (使用连接协商的消息头版本), 首个消息时记录连接的消息头版本
+// 	读取出错时释放解码器缓冲区, 返回的数据在下次读取前有效
+func (g *Agent) Decode(client Client, dec *codec.Decoder) (*codec.ClientHead, []byte, error) {
+	if v := client.Meta().CodecVer(); v != 0 {
+		dec.SetVersion(v)
+	}
+	head, data, err := dec.Decode()
+	if err != nil {
+		dec.Release()
+		return nil, nil, err
+	}
+	if client.Meta().CodecVer() == 0 {
+		client.Meta().Set(MetaCodecVer, dec.Version())
+	}
+	metrics.AgentMessage(metrics.DirIn, dec.Size())
+	return head, data, nil
+}
+
+// SetOnReceive 设置收到数据处理
+// 	数据引用解码器缓冲区 (不复制), 仅在调用期间有效, 返回后需要保留 (如异步处理) 时应复制
 func (g *Agent) SetOnReceive(fn func(Client, *codec.ClientHead, []byte) (*codec.ServerHead, []byte, error)) {
 	g.OnReceive = fn
 }
@@ -62,10 +115,22 @@ func (g *Agent) StartClient(client Client) {
 	g.clients[client.Id()] = client
 	g.Unlock()
 
+	defer client.Release() // 读取循环退出后释放解码器缓冲区
+
+	metrics.AgentConnection(Opts.Type, 1)
+	defer metrics.AgentConnection(Opts.Type, -1)
+
 	client.Log().Debugf("connected ...")
 
+	// 保存Meta缓存 (用于按客户端推送消息)
+	if metaCacheEnabled() {
+		if err := SaveMetaCache(client.Meta()); err != nil {
+			client.Log().Warn(color.Warn.Text("save meta cache error: %s", err))
+		}
+	}
+
 	// 接收消息处理
-	for {
+	for first := true; ; first = false {
 		// 接收消息
 		cHead, cData, err := client.Read()
 		if err != nil {
@@ -73,21 +138,31 @@ func (g *Agent) StartClient(client Client) {
 			break
 		}
 
-		// 处理接收的消息
+		// 握手消息
+		if cHead.Cmd == CmdHandshake {
+			if err := g.handshake(client, cHead, cData, first); err != nil {
+				client.Log().Warn(color.Warn.Text("handshake error: %s", err))
+				break
+			}
+			continue
+		}
+
+		// 处理接收的消息 (数据引用解码器缓冲区, 下次读取时复用)
 		sHead, sData, err := g.OnReceive(client, cHead, cData)
 		if err != nil {
 			break
 		}
 
-		// 响应消息
+		// 响应消息 (使用与客户端一致的消息头版本)
+		if sHead.Version == 0 {
+			sHead.Version = cHead.Version
+			sHead.Flags |= cHead.Flags & codec.FlagChecksum
+		}
 		if sHead.Code > 0 || len(sData) > 0 {
-			b, err := g.ServerCodec().Marshal(sHead, sData)
-			if err != nil {
-				client.Log().Warn(color.Warn.Text("marshal data error: %s", err))
+			if err := g.Send(client, sHead, sData); err != nil {
+				client.Log().Warn(color.Warn.Text("send data error: %s", err))
 				break
 			}
-
-			client.Write(b)
 		}
 	}
 
@@ -97,11 +172,100 @@ func (g *Agent) StartClient(client Client) {
 	delete(g.clients, client.Id())
 	g.Unlock()
 
+	// 删除Meta缓存
+	if metaCacheEnabled() {
+		if err := DeleteMetaCache(client.Meta()); err != nil {
+			client.Log().Warn(color.Warn.Text("delete meta cache error: %s", err))
+		}
+	}
+
 	g.OnDisconnect(client) // 连接断开处理
 
 	client.Log().Debug("disconnected ...")
 }
 
+// 处理握手消息 (仅允许作为连接的首个消息), 响应实际生效的握手参数
+// 	序列化方式在请求时由协议路由校验, 不支持的序列化方式返回错误码
+func (g *Agent) handshake(client Client, head *codec.ClientHead, data []byte, first bool) error {
+	if !first {
+		return errors.Invalid("handshake must be the first message")
+	}
+
+	hs := new(pb.Handshake)
+	if err := proto.Unmarshal(data, hs); err != nil {
+		return errors.Invalid("handshake data error: %s", err)
+	}
+	switch hs.Version {
+	case 0:
+	case uint32(codec.Version1), uint32(codec.Version2):
+		client.Meta().Set(MetaCodecVer, hs.Version)
+	default:
+		return errors.Invalid("unsupported msg head version: %d", hs.Version)
+	}
+	if hs.Serializer != "" {
+		client.Meta().Set(MetaSerializer, hs.Serializer)
+	}
+	hs.Serializer = client.Meta().Serializer()
+	hs.Version = uint32(client.Meta().CodecVer())
+
+	b, err := proto.Marshal(hs)
+	if err != nil {
+		return err
+	}
+
+	return g.Send(client, &codec.ServerHead{
+		Serial:  head.Serial,
+		Cmd:     CmdHandshake,
+		Version: head.Version,
+		Flags:   head.Flags & codec.FlagChecksum,
+	}, b)
+}
+
+// Auth 更新客户端认证信息, 设置认证成功并保存Meta缓存 (登录成功或切换角色后调用)
+// 	仅更新认证信息字段, 切换角色时删除原角色的Meta缓存
+func (g *Agent) Auth(client Client, values map[string]string) error {
+	gmt := client.Meta()
+	oldRoleId := gmt.RoleId()
+	for _, key := range authMetaKeys {
+		if val, ok := values[key]; ok {
+			gmt.Set(key, val)
+		}
+	}
+	client.SetAuthState(true)
+
+	if !metaCacheEnabled() {
+		return nil
+	}
+	if oldRoleId != 0 && oldRoleId != gmt.RoleId() && isRoleClient(oldRoleId, gmt.ClientId()) {
+		if err := meta.DeleteMetaCache(RoleCacheKey(oldRoleId)); err != nil {
+			return err
+		}
+	}
+	return SaveMetaCache(gmt)
+}
+
+// Send 编码消息并发送到客户端
+func (g *Agent) Send(client Client, head *codec.ServerHead, data []byte) error {
+	return codec.NewEncoder(&clientWriter{agent: g, client: client}, g.serverCodec).Encode(head, data)
+}
+
+// Write 发送消息到客户端 (记录监控指标)
+func (g *Agent) Write(client Client, b []byte) {
+	metrics.AgentMessage(metrics.DirOut, len(b))
+	client.Write(b)
+}
+
+// 客户端消息写入 (编码器的缓冲区写入后复用, 客户端异步发送, 因此需复制数据)
+type clientWriter struct {
+	agent  *Agent
+	client Client
+}
+
+func (w *clientWriter) Write(b []byte) (int, error) {
+	w.agent.Write(w.client, append([]byte(nil), b...))
+	return len(b), nil
+}
+
 // 获取客户端连接对象
 func (g *Agent) GetClient(val string, by ...string) Client {
 	g.RLock()
@@ -182,7 +346,7 @@ func (g *Agent) Broadcast(msg []byte, filter func(client Client) bool) {
 		}
 
 		if filter == nil || filter(client) {
-			client.Write(msg)
+			g.Write(client, msg)
 		}
 	}
 }
@@ -206,11 +370,86 @@ func (g *Agent) Run() error {
 		return errors.Server("Unsupported agent server type: %s", Opts.Type)
 	}
 
-	return g.server.Run()
+	if err := g.server.Run(); err != nil {
+		return err
+	}
+
+	// 健康检查 (按监听地址区分同一进程的多个网关)
+	g.healthName = HealthCheckName + ":" + g.Address()
+	if g.healthSrv = g.service(); g.healthSrv != nil {
+		g.healthSrv.AddHealthCheck(g.healthName, g.Health)
+	} else {
+		app.AddHealthCheck(g.healthName, g.Health)
+	}
+
+	// 上报连接数
+	if g.opts.ReportInterval > 0 {
+		g.exit = make(chan struct{})
+		go g.report(g.exit)
+	}
+
+	return nil
+}
+
+// 定时上报连接数到节点meta信息 (连接数变化时)
+func (g *Agent) report(exit chan struct{}) {
+	var last = -1
+
+	ticker := time.NewTicker(g.opts.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		if n, s := g.Count(), g.service(); n != last && s != nil {
+			if err := s.SetMetadata(map[string]string{app.MetaConnections: strconv.Itoa(n)}); err != nil {
+				log.Warn("[Agent] report connections error: %s", err)
+			} else {
+				last = n
+			}
+		}
+
+		select {
+		case <-exit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 网关所属服务
+func (g *Agent) service() *app.Service {
+	if g.opts.Service != nil {
+		return g.opts.Service
+	}
+	return app.Default()
+}
+
+// Health 健康检查 (网关服务已启动且未关闭)
+func (g *Agent) Health(_ context.Context) error {
+	g.RLock()
+	defer g.RUnlock()
+
+	if g.server == nil || g.closed {
+		return errors.Unavailable("agent server is not running")
+	}
+	return nil
 }
 
 // 关闭网关服务
 func (g *Agent) Close() {
+	g.Lock()
+	if g.healthSrv != nil {
+		g.healthSrv.RemoveHealthCheck(g.healthName)
+	} else if g.healthName != "" {
+		app.RemoveHealthCheck(g.healthName)
+	}
+	g.healthSrv, g.healthName = nil, ""
+	g.closed = true
+	if g.exit != nil {
+		close(g.exit)
+		g.exit = nil
+	}
+	g.Unlock()
+
 	if g.server != nil {
 		g.server.Close()
 	}
@@ -0,0 +1,24 @@
+// 网关测试辅助 (供各网关实现的测试使用)
+package agenttest
+
+import (
+	"github.com/cbwfree/micro-game/agent"
+)
+
+// Server 测试网关服务 (不监听端口)
+type Server struct {
+	name  string
+	agent *agent.Agent
+}
+
+func (s *Server) Name() string         { return s.name }
+func (s *Server) Agent() *agent.Agent  { return s.agent }
+func (s *Server) Opts() *agent.Options { return s.agent.Opts() }
+func (s *Server) Port() int            { return 0 }
+func (s *Server) Run() error           { return nil }
+func (s *Server) Close()               {}
+
+// NewServer 创建测试网关服务
+func NewServer(name string, g *agent.Agent) *Server {
+	return &Server{name: name, agent: g}
+}
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"fmt"
+	"github.com/cbwfree/micro-game/meta"
+	"github.com/cbwfree/micro-game/utils/errors"
+)
+
+var (
+	MetaCacheClientKey = "meta:client:%s" // 客户端Meta缓存
+	MetaCacheRoleKey   = "meta:role:%d"   // 角色Meta缓存
+)
+
+// 认证信息字段 (业务服务通过 UpdateAuth 同步到网关)
+var authMetaKeys = []string{MetaAccountId, MetaChannelUid, MetaServerId, MetaRoleId, MetaClientVer}
+
+// ClientCacheKey 客户端Meta缓存Key
+func ClientCacheKey(clientId string) string {
+	return fmt.Sprintf(MetaCacheClientKey, clientId)
+}
+
+// RoleCacheKey 角色Meta缓存Key
+func RoleCacheKey(roleId int64) string {
+	return fmt.Sprintf(MetaCacheRoleKey, roleId)
+}
+
+// SaveMetaCache 保存网关Meta (角色在线时同时保存角色Meta, 用于按角色推送消息)
+func SaveMetaCache(gmt *Meta) error {
+	metas := map[string]*meta.Meta{
+		ClientCacheKey(gmt.ClientId()): gmt.Meta,
+	}
+	if gmt.IsOnline() {
+		metas[RoleCacheKey(gmt.RoleId())] = gmt.Meta
+	}
+	return meta.MultiSaveMetaCache(metas)
+}
+
+// LoadClientMeta 获取客户端Meta缓存
+func LoadClientMeta(clientId string) (*Meta, error) {
+	return loadMetaCache(ClientCacheKey(clientId))
+}
+
+// LoadRoleMeta 获取角色Meta缓存
+func LoadRoleMeta(roleId int64) (*Meta, error) {
+	return loadMetaCache(RoleCacheKey(roleId))
+}
+
+// DeleteMetaCache 删除网关Meta缓存 (角色已在其它连接登录时, 保留角色Meta)
+func DeleteMetaCache(gmt *Meta) error {
+	keys := []string{ClientCacheKey(gmt.ClientId())}
+	if gmt.IsOnline() && isRoleClient(gmt.RoleId(), gmt.ClientId()) {
+		keys = append(keys, RoleCacheKey(gmt.RoleId()))
+	}
+	return meta.DeleteMetaCache(keys...)
+}
+
+// 角色Meta缓存是否属于指定的客户端连接
+func isRoleClient(roleId int64, clientId string) bool {
+	rmt, err := LoadRoleMeta(roleId)
+	return err == nil && rmt.ClientId() == clientId
+}
+
+// 是否启用Meta缓存 (已连接Redis)
+func metaCacheEnabled() bool {
+	return meta.Client() != nil
+}
+
+func loadMetaCache(key string) (*Meta, error) {
+	if !metaCacheEnabled() {
+		return nil, errors.Unavailable("meta cache is not enabled")
+	}
+	values, err := meta.LoadMetaCache(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, errors.NotFound("meta cache %s not found", key)
+	}
+	return &Meta{Meta: meta.ToMeta(values)}, nil
+}
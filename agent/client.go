@@ -12,9 +12,10 @@ type Client interface {
 	Meta() *Meta                              // 客户端上下文
 	Log() *logger.Helper                      // 日志对象
 	Closed() bool                             // 判断是否关闭
-	Read() (*codec.ClientHead, []byte, error) // 读取消息
+	Read() (*codec.ClientHead, []byte, error) // 读取消息 (返回的数据在下次读取前有效)
 	Write([]byte)                             // 发送消息
 	Close()                                   // 关闭连接
 	Destroy()                                 // 销毁连接 (丢弃任何未发送或未确认的数据)
+	Release()                                 // 释放读取缓冲区 (读取循环退出后调用)
 	SetAuthState(state bool)                  // 设置认证状态 (建立Socket连接后, 需要发送Token进行认证)
 }
@@ -5,6 +5,9 @@ import (
 	"github.com/cbwfree/micro-game/app"
 	"github.com/cbwfree/micro-game/meta"
 	"github.com/cbwfree/micro-game/utils/dtype"
+	"github.com/cbwfree/micro-game/utils/trace"
+	"github.com/micro/go-micro/v2/metadata"
+	"strconv"
 )
 
 const (
@@ -15,6 +18,10 @@ const (
 	MetaChannelUid = "Channel-Uid" // 渠道账户
 	MetaServerId   = "Server-Id"   // 服务器ID
 	MetaRoleId     = "Role-Id"     // 角色ID
+	MetaSerializer = "Serializer"  // 协议序列化方式 (proto/json/msgpack, 为空时使用默认设置)
+	MetaSerial     = "Serial"      // 请求序号 (仅在单次请求的metadata中传递)
+	MetaCodecVer   = "Codec-Ver"   // 消息头版本 (握手协商, 未握手时为旧版)
+	MetaTraceId    = "Trace-Id"    // 链路ID (同 trace.MetaTraceId, 仅在单次请求的metadata中传递)
 )
 
 // 网关上下文
@@ -50,13 +57,51 @@ func (ctx *Meta) RoleId() int64 {
 	return dtype.ParseInt64(ctx.Get(MetaRoleId))
 }
 
+func (ctx *Meta) Serializer() string {
+	return ctx.Get(MetaSerializer)
+}
+
+// CodecVer 消息头版本 (未确定时返回0)
+func (ctx *Meta) CodecVer() uint8 {
+	return uint8(dtype.ParseUint32(ctx.Get(MetaCodecVer)))
+}
+
+// Serial 请求序号
+func (ctx *Meta) Serial() (uint16, bool) {
+	val := ctx.Get(MetaSerial)
+	if val == "" {
+		return 0, false
+	}
+	return uint16(dtype.ParseUint32(val)), true
+}
+
+// TraceId 链路ID
+func (ctx *Meta) TraceId() string {
+	return ctx.Get(MetaTraceId)
+}
+
+// RequestContext 附加请求序号及新链路ID的上下文 (网关转发请求时使用)
+func (ctx *Meta) RequestContext(serial uint16) context.Context {
+	c := metadata.Set(ctx.Context(), MetaSerial, strconv.Itoa(int(serial)))
+	return trace.NewContext(c, trace.NewTraceId())
+}
+
 func (ctx *Meta) IsOnline() bool {
 	return ctx.Get(MetaRoleId) != ""
 }
 
-// NewMeta 实例化metadata数据
+// NewMeta 实例化metadata数据 (使用默认服务, 未创建默认服务时服务名称及节点ID为空)
 func NewMeta(clientId string) *Meta {
-	mt := meta.NewMeta(app.Name(), app.Id(), map[string]string{
+	return newMeta(app.Default(), clientId)
+}
+
+// 实例化metadata数据 (服务名称及节点ID为客户端所在的网关节点)
+func newMeta(s *app.Service, clientId string) *Meta {
+	var name, id string
+	if s != nil {
+		name, id = s.Name(), s.Id()
+	}
+	mt := meta.NewMeta(name, id, map[string]string{
 		MetaClientId:   clientId,
 		MetaClientIp:   "",
 		MetaClientVer:  "",
@@ -64,6 +109,7 @@ func NewMeta(clientId string) *Meta {
 		MetaChannelUid: "",
 		MetaServerId:   "",
 		MetaRoleId:     "",
+		MetaSerializer: "",
 	})
 	return &Meta{Meta: mt}
 }
@@ -2,11 +2,14 @@ package agent
 
 import (
 	"time"
+
+	"github.com/cbwfree/micro-game/app"
 )
 
 var (
-	DefaultReadTimeout  = 15 * time.Second // 默认请求超时时间
-	DefaultWriteTimeout = 15 * time.Second // 默认请求超时时间
+	DefaultReadTimeout    = 15 * time.Second // 默认请求超时时间
+	DefaultWriteTimeout   = 15 * time.Second // 默认请求超时时间
+	DefaultReportInterval = 10 * time.Second // 默认连接数上报间隔
 )
 
 type Option func(o *Options)
@@ -20,6 +23,8 @@ type Options struct {
 	HeartbeatDeadline time.Duration // 心跳等待
 	ReadTimeout       time.Duration // 读超时
 	WriteTimeout      time.Duration // 写超时
+	ReportInterval    time.Duration // 连接数上报间隔 (节点meta信息), 为0不上报
+	Service           *app.Service  // 所属服务 (连接数上报到该服务节点), 为空时使用默认服务
 }
 
 func (o *Options) Init(opts ...Option) {
@@ -70,6 +75,18 @@ func WithWriteTimeout(t time.Duration) Option {
 	}
 }
 
+func WithReportInterval(t time.Duration) Option {
+	return func(o *Options) {
+		o.ReportInterval = t
+	}
+}
+
+func WithService(s *app.Service) Option {
+	return func(o *Options) {
+		o.Service = s
+	}
+}
+
 func NewOptions(opts ...Option) *Options {
 	o := &Options{
 		Address:           Opts.Port,
@@ -79,6 +96,7 @@ func NewOptions(opts ...Option) *Options {
 		HeartbeatDeadline: time.Duration(Opts.HeartbeatDeadline) * time.Second,
 		ReadTimeout:       DefaultReadTimeout,
 		WriteTimeout:      DefaultWriteTimeout,
+		ReportInterval:    DefaultReportInterval,
 	}
 	o.Init(opts...)
 	return o
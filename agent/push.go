@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"github.com/cbwfree/micro-game/app"
+	"github.com/cbwfree/micro-game/codec"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/pb"
+)
+
+// 网关RPC方法
+const (
+	PushMethod = "Gate.Push" // 推送消息
+	AuthMethod = "Gate.Auth" // 更新认证信息
+)
+
+// Gate 网关RPC服务 (网关节点通过 app.AddHandler 注册)
+type Gate struct {
+	agent *Agent
+}
+
+// Push 推送消息到客户端
+func (g *Gate) Push(_ context.Context, req *pb.Push, _ *pb.None) error {
+	client := g.agent.GetClient(req.ClientId)
+	if client == nil || client.Closed() {
+		return errors.NotFound("client %s not found", req.ClientId)
+	}
+
+	head := &codec.ServerHead{
+		Cmd:     req.Cmd,
+		Code:    req.Code,
+		Version: client.Meta().CodecVer(),
+	}
+	if head.Version == codec.Version2 {
+		head.Flags |= codec.FlagPush
+	}
+
+	return g.agent.Send(client, head, req.Data)
+}
+
+// Auth 更新客户端认证信息, 并保存Meta缓存
+func (g *Gate) Auth(_ context.Context, req *pb.Auth, _ *pb.None) error {
+	client := g.agent.GetClient(req.ClientId)
+	if client == nil || client.Closed() {
+		return errors.NotFound("client %s not found", req.ClientId)
+	}
+	return g.agent.Auth(client, req.Meta)
+}
+
+// UpdateAuth 同步认证信息到客户端所在的网关 (业务服务登录成功或切换角色后调用)
+// 	s 为发起调用的服务, 为空时使用默认服务
+func UpdateAuth(ctx context.Context, gmt *Meta, s ...*app.Service) error {
+	srv := app.Default()
+	if len(s) > 0 && s[0] != nil {
+		srv = s[0]
+	}
+	if srv == nil {
+		return errors.Unavailable("no service to update auth")
+	}
+
+	values := make(map[string]string, len(authMetaKeys))
+	for _, key := range authMetaKeys {
+		values[key] = gmt.Get(key)
+	}
+	in := &pb.Auth{
+		ClientId: gmt.ClientId(),
+		Meta:     values,
+	}
+	return srv.CallNode(ctx, gmt.Name(), AuthMethod, in, new(pb.None), gmt.Id())
+}
+
+func NewGate(agent *Agent) *Gate {
+	return &Gate{agent: agent}
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/lucas-clemente/quic-go"
 	"github.com/micro/go-micro/v2/logger"
-	"io"
 	"sync"
 	"time"
 )
@@ -18,6 +17,7 @@ type Client struct {
 	id       string         // Client ID
 	server   agent.Server   // 服务器
 	conn     quic.Stream    // socket连接
+	decoder  *codec.Decoder // 消息解码器
 	meta     *agent.Meta    // 客户端上下文
 	log      *logger.Helper // 日志对象
 	waitAuth *time.Timer    // 等待认证定时器
@@ -78,27 +78,7 @@ func (c *Client) SetAuthState(state bool) {
 func (c *Client) Read() (*codec.ClientHead, []byte, error) {
 	_ = c.conn.SetReadDeadline(time.Now().Add(c.server.Opts().ReadTimeout))
 
-	clientCodec := c.server.Agent().ClientCodec()
-	headLen := clientCodec.HeadLen()
-	headBuf := make([]byte, headLen)
-	if _, err := io.ReadFull(c.conn, headBuf); err != nil {
-		return nil, nil, err
-	}
-
-	// 解析消息头
-	head, _, err := clientCodec.Unmarshal(headBuf)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	dataBuf := make([]byte, head.DataLen)
-	if head.DataLen > 0 {
-		if _, err := io.ReadFull(c.conn, dataBuf); err != nil {
-			return nil, nil, err
-		}
-	}
-
-	return head, dataBuf, nil
+	return c.server.Agent().Decode(c, c.decoder)
 }
 
 // 发送消息
@@ -149,6 +129,11 @@ func (c *Client) Destroy() {
 	c.doDestroy()
 }
 
+// 释放读取缓冲区 (读取循环退出后调用)
+func (c *Client) Release() {
+	c.decoder.Release()
+}
+
 // 关闭操作
 func (c *Client) doDestroy() {
 	_ = c.conn.Close()
@@ -163,11 +148,12 @@ func NewClient(server agent.Server, conn quic.Stream, ip string) agent.Client {
 		id:        uuid.New().String(),
 		server:    server,
 		conn:      conn,
+		decoder:   server.Agent().NewDecoder(conn),
 		writeChan: make(chan []byte, 100),
 	}
 
 	// 设置客户端信息
-	c.meta = agent.NewMeta(c.id)
+	c.meta = server.Agent().NewMeta(c.id)
 	c.meta.Set(agent.MetaClientIp, ip)
 
 	c.log = log.Logger.WithFields(map[string]interface{}{
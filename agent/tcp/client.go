@@ -7,7 +7,6 @@ import (
 	"github.com/cbwfree/micro-game/utils/log"
 	"github.com/google/uuid"
 	"github.com/micro/go-micro/v2/logger"
-	"io"
 	"net"
 	"sync"
 	"time"
@@ -18,6 +17,7 @@ type Client struct {
 	id       string         // Client ID
 	server   agent.Server   // 服务器
 	conn     net.Conn       // socket连接
+	decoder  *codec.Decoder // 消息解码器
 	meta     *agent.Meta    // 客户端上下文
 	log      *logger.Helper // 日志对象
 	waitAuth *time.Timer    // 等待认证定时器
@@ -76,27 +76,7 @@ func (c *Client) SetAuthState(state bool) {
 
 // 发送消息
 func (c *Client) Read() (*codec.ClientHead, []byte, error) {
-	clientCodec := c.server.Agent().ClientCodec()
-	headLen := clientCodec.HeadLen()
-	headBuf := make([]byte, headLen)
-	if _, err := io.ReadFull(c.conn, headBuf); err != nil {
-		return nil, nil, err
-	}
-
-	// 解析消息头
-	head, _, err := clientCodec.Unmarshal(headBuf)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	dataBuf := make([]byte, head.DataLen)
-	if head.DataLen > 0 {
-		if _, err := io.ReadFull(c.conn, dataBuf); err != nil {
-			return nil, nil, err
-		}
-	}
-
-	return head, dataBuf, nil
+	return c.server.Agent().Decode(c, c.decoder)
 }
 
 // 发送消息
@@ -147,6 +127,11 @@ func (c *Client) Destroy() {
 	c.doDestroy()
 }
 
+// 释放读取缓冲区 (读取循环退出后调用)
+func (c *Client) Release() {
+	c.decoder.Release()
+}
+
 // 关闭操作
 func (c *Client) doDestroy() {
 	_ = c.conn.(*net.TCPConn).SetLinger(0)
@@ -162,11 +147,12 @@ func NewClient(server agent.Server, conn net.Conn, ip string) agent.Client {
 		id:        uuid.New().String(),
 		server:    server,
 		conn:      conn,
+		decoder:   server.Agent().NewDecoder(conn),
 		writeChan: make(chan []byte, 100),
 	}
 
 	// 设置客户端信息
-	c.meta = agent.NewMeta(c.id)
+	c.meta = server.Agent().NewMeta(c.id)
 	c.meta.Set(agent.MetaClientIp, ip)
 
 	c.log = log.Logger.WithFields(map[string]interface{}{
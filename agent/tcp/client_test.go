@@ -0,0 +1,205 @@
+package tcp
+
+import (
+	"github.com/cbwfree/micro-game/agent"
+	"github.com/cbwfree/micro-game/agent/agenttest"
+	"github.com/cbwfree/micro-game/app"
+	"github.com/cbwfree/micro-game/codec"
+	"github.com/cbwfree/micro-game/utils/pb"
+	"github.com/golang/protobuf/proto"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	app.New("agent-test", "latest")
+	os.Exit(m.Run())
+}
+
+// 创建内存连接的客户端
+func newTestClient(t *testing.T) (agent.Client, net.Conn) {
+	g := agent.NewAgent(nil, agent.WithWaitAuthTime(time.Minute))
+	sc, cc := net.Pipe()
+	t.Cleanup(func() {
+		_ = sc.Close()
+		_ = cc.Close()
+	})
+	return NewClient(agenttest.NewServer("tcp", g), sc, "127.0.0.1"), cc
+}
+
+func TestClient_Read(t *testing.T) {
+	client, conn := newTestClient(t)
+	c := codec.NewClient()
+
+	go func() {
+		enc := codec.NewClientEncoder(conn, c)
+		_ = enc.Encode(&codec.ClientHead{Serial: 1, Cmd: 10001}, []byte("v1"))
+		_ = enc.Encode(&codec.ClientHead{Serial: 2, Cmd: 10002}, nil)
+	}()
+
+	for i, want := range []string{"v1", ""} {
+		head, data, err := client.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head.Serial != uint16(i+1) || string(data) != want {
+			t.Fatalf("unexpected head: %+v, data: %s", head, data)
+		}
+	}
+	if client.Meta().CodecVer() != codec.Version1 {
+		t.Fatalf("expected codec version 1, got: %d", client.Meta().CodecVer())
+	}
+}
+
+func TestClient_ReadVersion2(t *testing.T) {
+	client, conn := newTestClient(t)
+	client.Meta().Set(agent.MetaCodecVer, codec.Version2) // 已握手协商新版消息头
+
+	go func() {
+		_ = codec.NewClientEncoder(conn, codec.NewClient()).Encode(&codec.ClientHead{
+			Serial:  1,
+			Cmd:     10001,
+			Version: codec.Version2,
+			Flags:   codec.FlagChecksum,
+		}, []byte("v2"))
+	}()
+
+	head, data, err := client.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Version != codec.Version2 || string(data) != "v2" || client.Meta().CodecVer() != codec.Version2 {
+		t.Fatalf("unexpected head: %+v, data: %s", head, data)
+	}
+}
+
+// 未握手的连接固定使用旧版消息头, Serial 首字节与新版标识相同时不会误判
+func TestClient_ReadLegacySerial(t *testing.T) {
+	client, conn := newTestClient(t)
+
+	go func() {
+		_ = codec.NewClientEncoder(conn, codec.NewClient()).Encode(&codec.ClientHead{Serial: 0xF2F2, Cmd: 10001}, []byte("v1"))
+	}()
+
+	head, data, err := client.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Version != codec.Version1 || head.Serial != 0xF2F2 || head.Cmd != 10001 || string(data) != "v1" {
+		t.Fatalf("unexpected head: %+v, data: %s", head, data)
+	}
+}
+
+func TestClient_ReadTruncated(t *testing.T) {
+	client, conn := newTestClient(t)
+
+	go func() {
+		raw, _ := codec.NewClient().Marshal(&codec.ClientHead{Cmd: 10001}, []byte("truncated"))
+		_, _ = conn.Write(raw[:len(raw)-3])
+		_ = conn.Close()
+	}()
+
+	if _, _, err := client.Read(); err == nil {
+		t.Fatal("expected truncated frame error")
+	}
+}
+
+func TestClient_ReadOversized(t *testing.T) {
+	client, conn := newTestClient(t)
+
+	go func() {
+		// 只发送消息头, 声明超过限制的数据长度
+		raw, _ := codec.NewClient().Marshal(&codec.ClientHead{Cmd: 10001}, nil)
+		raw[len(raw)-1] = 0xFF
+		raw[len(raw)-4] = 0xFF
+		_, _ = conn.Write(raw)
+	}()
+
+	if _, _, err := client.Read(); err == nil {
+		t.Fatal("expected oversized frame error")
+	}
+}
+
+func TestAgent_Handshake(t *testing.T) {
+	client, conn := newTestClient(t)
+	g := client.Server().Agent()
+
+	serializers := make(chan string, 1)
+	g.SetOnReceive(func(c agent.Client, head *codec.ClientHead, _ []byte) (*codec.ServerHead, []byte, error) {
+		serializers <- c.Meta().Serializer()
+		return &codec.ServerHead{Serial: head.Serial, Cmd: head.Cmd}, []byte("ok"), nil
+	})
+	g.SetOnDisconnect(func(agent.Client) {})
+	go g.StartClient(client)
+
+	enc := codec.NewClientEncoder(conn, codec.NewClient())
+	dec := codec.NewServerDecoder(conn, codec.NewServer())
+	defer dec.Release()
+
+	// 握手消息使用旧版消息头
+	dec.SetVersion(codec.Version1)
+	data, _ := proto.Marshal(&pb.Handshake{Serializer: "json", Version: uint32(codec.Version2)})
+	if err := enc.Encode(&codec.ClientHead{Serial: 1, Cmd: agent.CmdHandshake}, data); err != nil {
+		t.Fatal(err)
+	}
+
+	head, data, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := new(pb.Handshake)
+	if err := proto.Unmarshal(data, hs); err != nil {
+		t.Fatal(err)
+	}
+	if head.Cmd != agent.CmdHandshake || head.Serial != 1 || hs.Serializer != "json" || hs.Version != uint32(codec.Version2) {
+		t.Fatalf("unexpected handshake response: %+v, %+v", head, hs)
+	}
+
+	// 握手后使用新版消息头 (Serial 首字节与新版标识相同)
+	dec.SetVersion(codec.Version2)
+	if err := enc.Encode(&codec.ClientHead{Serial: 0xF2F2, Cmd: 10001, Version: codec.Version2, Flags: codec.FlagChecksum}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := <-serializers; s != "json" {
+		t.Fatalf("unexpected serializer: %s", s)
+	}
+	if head, data, err := dec.Decode(); err != nil || head.Version != codec.Version2 || head.Serial != 0xF2F2 || string(data) != "ok" {
+		t.Fatalf("unexpected response: %+v, %s, %v", head, data, err)
+	}
+
+	// 握手消息只能作为首个消息, 否则断开连接
+	if err := enc.Encode(&codec.ClientHead{Serial: 3, Cmd: agent.CmdHandshake, Version: codec.Version2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := dec.Decode(); err == nil {
+		t.Fatal("expected connection closed")
+	}
+}
+
+// 收到的数据引用解码器缓冲区, 保留时需复制
+func TestAgent_ReceiveData(t *testing.T) {
+	client, conn := newTestClient(t)
+	g := client.Server().Agent()
+
+	received := make(chan []byte, 2)
+	g.SetOnReceive(func(_ agent.Client, head *codec.ClientHead, data []byte) (*codec.ServerHead, []byte, error) {
+		received <- append([]byte(nil), data...)
+		return &codec.ServerHead{Serial: head.Serial, Cmd: head.Cmd}, nil, nil
+	})
+	disconnected := make(chan struct{})
+	g.SetOnDisconnect(func(agent.Client) { close(disconnected) })
+	go g.StartClient(client)
+
+	enc := codec.NewClientEncoder(conn, codec.NewClient())
+	_ = enc.Encode(&codec.ClientHead{Serial: 1, Cmd: 10001}, []byte("first"))
+	_ = enc.Encode(&codec.ClientHead{Serial: 2, Cmd: 10001}, []byte("again"))
+	first, second := <-received, <-received
+	if string(first) != "first" || string(second) != "again" {
+		t.Fatalf("unexpected data: %s, %s", first, second)
+	}
+
+	_ = conn.Close()
+	<-disconnected
+}
@@ -18,6 +18,7 @@ type Client struct {
 	id        string          // Client ID
 	server    agent.Server    // 服务器
 	conn      *websocket.Conn // socket连接
+	decoder   *codec.Decoder  // 消息解码器
 	meta      *agent.Meta     // 客户端上下文
 	log       *logger.Helper  // 日志对象
 	waitAuth  *time.Timer     // 等待认证定时器
@@ -77,17 +78,14 @@ func (c *Client) SetAuthState(state bool) {
 
 // 发送消息
 func (c *Client) Read() (*codec.ClientHead, []byte, error) {
-	_, b, err := c.conn.ReadMessage()
+	_, r, err := c.conn.NextReader()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	head, data, err := c.server.Agent().ClientCodec().Unmarshal(b)
-	if err != nil {
-		return nil, nil, err
-	}
+	c.decoder.Reset(r)
 
-	return head, data, err
+	return c.server.Agent().Decode(c, c.decoder)
 }
 
 // 发送消息
@@ -138,6 +136,11 @@ func (c *Client) Destroy() {
 	c.doDestroy()
 }
 
+// 释放读取缓冲区 (读取循环退出后调用)
+func (c *Client) Release() {
+	c.decoder.Release()
+}
+
 // 关闭操作
 func (c *Client) doDestroy() {
 	_ = c.conn.UnderlyingConn().(*net.TCPConn).SetLinger(0)
@@ -153,12 +156,13 @@ func NewClient(server agent.Server, conn *websocket.Conn, ip string) agent.Clien
 		id:        uuid.New().String(),
 		server:    server,
 		conn:      conn,
+		decoder:   server.Agent().NewDecoder(nil),
 		writeChan: make(chan []byte, 100),
 		heartbeat: time.NewTicker(server.Opts().HeartbeatInterval),
 	}
 
 	// 设置客户端信息
-	c.meta = agent.NewMeta(c.id)
+	c.meta = server.Agent().NewMeta(c.id)
 	c.meta.Set(agent.MetaClientIp, ip)
 
 	c.log = log.Logger.WithFields(map[string]interface{}{
@@ -169,17 +173,24 @@ func NewClient(server agent.Server, conn *websocket.Conn, ip string) agent.Clien
 	// 连接成功后, 启动认证超时验证
 	c.SetAuthState(false)
 
-	// 心跳处理
+	// 心跳处理 (写入协程退出后停止)
+	done := make(chan struct{})
 	go func() {
 		c.conn.SetPongHandler(func(_ string) error {
 			c.log.Debugf("[Heartbeat] PONG ...")
 			return nil
 		})
 
-		for range c.heartbeat.C {
-			deadline := time.Now().Add(c.server.Opts().HeartbeatDeadline)
-			if err := conn.WriteControl(websocket.PingMessage, []byte("ping"), deadline); err != nil {
-				break
+	loop:
+		for {
+			select {
+			case <-done:
+				break loop
+			case <-c.heartbeat.C:
+				deadline := time.Now().Add(c.server.Opts().HeartbeatDeadline)
+				if err := conn.WriteControl(websocket.PingMessage, []byte("ping"), deadline); err != nil {
+					break loop
+				}
 			}
 		}
 
@@ -189,10 +200,8 @@ func NewClient(server agent.Server, conn *websocket.Conn, ip string) agent.Clien
 	// 异步处理推送消息
 	go func() {
 		defer func() {
-			if c.heartbeat != nil {
-				c.heartbeat.Stop()
-				c.heartbeat = nil
-			}
+			c.heartbeat.Stop()
+			close(done)
 		}()
 
 		for b := range c.writeChan {
@@ -0,0 +1,104 @@
+package websocket
+
+import (
+	"github.com/cbwfree/micro-game/agent"
+	"github.com/cbwfree/micro-game/agent/agenttest"
+	"github.com/cbwfree/micro-game/app"
+	"github.com/cbwfree/micro-game/codec"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	app.New("agent-test", "latest")
+	os.Exit(m.Run())
+}
+
+// 读取结果
+type readResult struct {
+	head *codec.ClientHead
+	data string
+	err  error
+}
+
+// 启动内存WebSocket服务, 服务端读取的消息写入 results
+func newTestConn(t *testing.T) (*websocket.Conn, chan readResult) {
+	g := agent.NewAgent(nil, agent.WithWaitAuthTime(time.Minute), agent.WithHeartbeatInterval(time.Minute))
+	results := make(chan readResult, 10)
+
+	upgrader := &websocket.Upgrader{}
+	hs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := NewClient(agenttest.NewServer("websocket", g), conn, "127.0.0.1")
+		for {
+			head, data, err := client.Read()
+			results <- readResult{head: head, data: string(data), err: err}
+			if err != nil {
+				client.Close()
+				return
+			}
+		}
+	}))
+	t.Cleanup(hs.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn, results
+}
+
+func TestClient_Read(t *testing.T) {
+	conn, results := newTestConn(t)
+	c := codec.NewClient()
+
+	for i := 1; i <= 3; i++ {
+		raw, _ := c.Marshal(&codec.ClientHead{Serial: uint16(i), Cmd: 10001}, []byte("data"))
+		if err := conn.WriteMessage(websocket.BinaryMessage, raw); err != nil {
+			t.Fatal(err)
+		}
+		res := <-results
+		if res.err != nil {
+			t.Fatal(res.err)
+		}
+		if res.head.Serial != uint16(i) || res.head.Cmd != 10001 || res.data != "data" {
+			t.Fatalf("unexpected head: %+v, data: %s", res.head, res.data)
+		}
+	}
+}
+
+func TestClient_ReadTruncated(t *testing.T) {
+	conn, results := newTestConn(t)
+
+	raw, _ := codec.NewClient().Marshal(&codec.ClientHead{Cmd: 10001}, []byte("truncated"))
+	if err := conn.WriteMessage(websocket.BinaryMessage, raw[:len(raw)-3]); err != nil {
+		t.Fatal(err)
+	}
+	if res := <-results; res.err == nil {
+		t.Fatal("expected truncated frame error")
+	}
+}
+
+func TestClient_ReadOversized(t *testing.T) {
+	conn, results := newTestConn(t)
+
+	raw, _ := codec.NewClient().Marshal(&codec.ClientHead{Cmd: 10001}, nil)
+	raw[len(raw)-1] = 0xFF
+	raw[len(raw)-4] = 0xFF
+	if err := conn.WriteMessage(websocket.BinaryMessage, raw); err != nil {
+		t.Fatal(err)
+	}
+	if res := <-results; res.err == nil {
+		t.Fatal("expected oversized frame error")
+	}
+}
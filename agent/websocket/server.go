@@ -68,8 +68,10 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 启动客户端
-	s.agent.StartClient(NewClient(s, conn, tool.GetHttpRealIP(r)))
+	// 启动客户端 (可以通过 ?serializer=json 或握手消息指定协议序列化方式)
+	client := NewClient(s, conn, tool.GetHttpRealIP(r))
+	client.Meta().Set(agent.MetaSerializer, r.URL.Query().Get("serializer"))
+	s.agent.StartClient(client)
 }
 
 // 启动
@@ -3,10 +3,13 @@ package app
 import (
 	"context"
 	"fmt"
-	"github.com/google/gops/agent"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
 	"github.com/micro/go-micro/v2/config/cmd"
 	"github.com/micro/go-micro/v2/transport"
+	"github.com/robfig/cron/v3"
 	"sync"
+	"sync/atomic"
 
 	"github.com/micro/cli/v2"
 	"github.com/micro/go-micro/v2"
@@ -21,239 +24,475 @@ import (
 	"github.com/micro/go-micro/v2/broker/nats"
 	"github.com/micro/go-micro/v2/registry/etcd"
 
+	"github.com/cbwfree/micro-game/utils/errors"
 	"github.com/cbwfree/micro-game/utils/log"
 	"github.com/cbwfree/micro-game/utils/pb"
+
+	rds "github.com/cbwfree/micro-game/store/redis"
 )
 
+// 关闭服务事件
+var CancelEvent = NewEvent("cancel", new(pb.Cancel))
+
 var (
-	appInstance *app
-	srvOnce     sync.Once
+	defaultService *Service
+	defaultMu      sync.RWMutex
+	parsedFlags    int32 // 进程参数是否已由服务解析
 )
 
-// 服务单例
-func APP() *app {
-	srvOnce.Do(func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		appInstance = &app{
-			ctx:       ctx,
-			cancel:    cancel,
-			publisher: make(map[string]micro.Publisher),
-		}
-	})
-	return appInstance
+// Default 默认服务 (包级函数均作用于默认服务)
+func Default() *Service {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+
+	return defaultService
+}
+
+// SetDefault 设置默认服务 (同一进程运行多个服务时, 指定网关、协议路由等使用的服务)
+func SetDefault(s *Service) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	defaultService = s
+}
+
+// APP 默认服务
+// 	Deprecated: 使用 Default
+func APP() *Service {
+	return Default()
 }
 
-// 当前服务
-type app struct {
-	sync.Mutex
+// Service 服务实例 (同一进程可创建多个服务)
+type Service struct {
+	sync.RWMutex
 	ctx    context.Context
 	cancel context.CancelFunc
 
 	srv       micro.Service
-	publisher map[string]micro.Publisher // 订阅
-}
+	publisher map[string]micro.Publisher // 发布者
+	subOpts   map[string]*SubOptions     // 订阅选项
+	metadata  map[string]string          // 动态meta信息
+	running   int32                      // 是否运行中
 
-func (a *app) Service() micro.Service {
-	return a.srv
-}
+	delayOnce sync.Once
+	scheduler *rds.Scheduler // 延迟消息调度器
 
-func (a *app) Cancel() {
-	a.cancel()
+	cron     *cron.Cron            // 定时任务调度器 (服务启动时创建)
+	cronJobs map[string]*cronEntry // 定时任务
 }
 
-func (a *app) newService(name string, version string, flags []cli.Flag) {
+func (s *Service) newService(name string, version string, flags []cli.Flag) {
 	var opts = []micro.Option{
-		micro.Context(a.ctx),
-		micro.Cmd(cmd.NewCmd(
+		micro.Context(s.ctx),
+		micro.Cmd(cmd.NewCmd(append([]cmd.Option{
 			cmd.Name(name),
 			cmd.Description(fmt.Sprintf("a %s service", name)),
 			cmd.Version(version),
-		)),
+		}, pluginCmdOptions()...)...)),
 		micro.Client(cgrpc.NewClient()),
 		micro.Server(sgrpc.NewServer(
+			server.Id(uuid.New().String()), // 同一进程的多个服务使用不同节点ID
 			server.Name(name),
 			server.Version(version),
 		)),
 		micro.Transport(tgrpc.NewTransport(transport.Secure(true))),
-		micro.Broker(nats.NewBroker()),     // nats
-		micro.Registry(etcd.NewRegistry()), // ectd
-		micro.WrapHandler(serverWrapper),
-		micro.WrapSubscriber(subscriberWrapper),
-		micro.BeforeStart(func() error {
-			if Opts.PsAddr != "" {
-				if err := agent.Listen(agent.Options{Addr: Opts.PsAddr}); err != nil {
-					return err
-				}
-			}
+		micro.Broker(nats.NewBroker()),     // nats (--broker 参数选择)
+		micro.Registry(etcd.NewRegistry()), // ectd (--registry 参数选择)
+		micro.WrapHandler(s.serverWrapper),
+		micro.WrapSubscriber(s.subscriberWrapper),
+		micro.WrapClient(s.clientWrapper),
+		micro.BeforeStart(s.startShared),
+		micro.AfterStop(s.stopShared),
+		micro.AfterStart(s.startDelay),
+		micro.BeforeStop(s.stopDelay),
+		micro.AfterStart(s.startCron),
+		micro.BeforeStop(s.stopCron),
+		micro.AfterStart(func() error {
+			s.setReady(true)
+			return nil
+		}),
+		micro.BeforeStop(func() error {
+			s.setReady(false)
 			return nil
 		}),
 	}
-	a.srv = micro.NewService(opts...)
+	s.srv = micro.NewService(opts...)
 
 	// 重载Cmd参数, 省略用不上的参数
-	a.srv.Options().Cmd.App().Flags = flags
+	s.srv.Options().Cmd.App().Flags = flags
+
+	// 插件选择
+	s.srv.Options().Cmd.App().Before = s.pluginBefore(s.srv.Options().Cmd.App().Before)
 }
 
-func (a *app) initService(opts ...micro.Option) {
-	a.srv.Init(opts...)
+// Init 初始化服务 (解析参数)
+func (s *Service) Init(opts ...micro.Option) {
+	s.srv.Init(opts...)
 }
 
-// New 创建服务
-func New(name string, version string, extra ...[]cli.Flag) {
-	if APP().srv != nil {
-		return
+// Srv 获取服务对象
+func (s *Service) Srv() micro.Service {
+	return s.srv
+}
+
+// Service 获取服务对象 (与 Srv 相同, 兼容旧版 APP().Service())
+func (s *Service) Service() micro.Service {
+	return s.srv
+}
+
+// Client 获取客户端
+func (s *Service) Client() client.Client {
+	return s.srv.Client()
+}
+
+// Server 获取服务端
+func (s *Service) Server() server.Server {
+	return s.srv.Server()
+}
+
+// Run 启动服务 (阻塞至服务关闭)
+func (s *Service) Run() error {
+	return s.srv.Run()
+}
+
+// Close 关闭服务
+func (s *Service) Close() {
+	s.cancel()
+}
+
+// Cancel 关闭服务 (与 Close 相同, 兼容旧版 APP().Cancel())
+func (s *Service) Cancel() {
+	s.cancel()
+}
+
+// CancelNode 通知指定服务节点关闭
+func (s *Service) CancelNode(name string, id ...string) error {
+	in := &pb.Cancel{Name: name}
+	if len(id) > 0 {
+		in.NodeId = id[0]
 	}
+	return s.PubEvent(context.TODO(), CancelEvent, in)
+}
 
-	// 合并flags
-	var flags = defaultFlags
-	if len(extra) > 0 {
-		for _, ex := range extra {
-			flags = append(flags, ex...)
+// Version 获取服务版本
+func (s *Service) Version() string {
+	return s.Server().Options().Version
+}
+
+// Id 获取服务UUID
+func (s *Service) Id() string {
+	return s.Server().Options().Id
+}
+
+// Name 获取服务名称
+func (s *Service) Name() string {
+	return s.Server().Options().Name
+}
+
+// NameId 获取服务节点ID
+func (s *Service) NameId() string {
+	return fmt.Sprintf("%s-%s", s.Name(), s.Id())
+}
+
+// Registry 获取服务发现注册信息
+func (s *Service) Registry() registry.Registry {
+	return s.srv.Options().Registry
+}
+
+// GetServices 获取服务节点列表
+func (s *Service) GetServices(name string) []*registry.Service {
+	res, _ := s.Registry().GetService(name)
+	return res
+}
+
+// AddPub 注册发布者
+func (s *Service) AddPub(names ...string) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, name := range names {
+		s.publisher[name] = micro.NewEvent(name, s.Client())
+	}
+}
+
+// GetPub 获取发布者 (已定义事件的主题自动注册, 未注册时返回nil)
+func (s *Service) GetPub(name string) micro.Publisher {
+	s.RLock()
+	p, ok := s.publisher[name]
+	s.RUnlock()
+	if ok {
+		return p
+	}
+
+	if _, ok := GetEvent(name); !ok {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if p, ok = s.publisher[name]; !ok {
+		p = micro.NewEvent(name, s.Client())
+		s.publisher[name] = p
+	}
+	return p
+}
+
+// Pub 发布消息 (主题未注册时返回错误)
+func (s *Service) Pub(name string, msg interface{}, opts ...client.PublishOption) error {
+	return s.PubCtx(context.TODO(), name, msg, opts...)
+}
+
+// PubCtx 发布消息 (上下文中没有消息ID时生成新的消息ID)
+func (s *Service) PubCtx(ctx context.Context, name string, msg interface{}, opts ...client.PublishOption) error {
+	if e, ok := GetEvent(name); ok {
+		if err := e.check(msg); err != nil {
+			return err
 		}
 	}
 
+	p := s.GetPub(name)
+	if p == nil {
+		return errors.NotFound("publisher %s not registered", name)
+	}
+	return p.Publish(withMessageId(ctx), msg, opts...)
+}
+
+// PubEvent 发布事件
+func (s *Service) PubEvent(ctx context.Context, e *Event, msg proto.Message, opts ...client.PublishOption) error {
+	return s.PubCtx(ctx, e.topic, msg, opts...)
+}
+
+// AddSub 注册订阅
+func (s *Service) AddSub(name string, h interface{}, queue ...bool) error {
+	var opts []SubOption
+	if len(queue) > 0 && queue[0] {
+		opts = append(opts, SubQueue())
+	}
+	return s.Subscribe(name, h, opts...)
+}
+
+func (s *Service) AddSubQueue(name string, h interface{}) error {
+	return s.Subscribe(name, h, SubQueue())
+}
+
+// SubEvent 订阅事件 (处理函数签名 func(context.Context, *Message) error)
+func (s *Service) SubEvent(e *Event, h interface{}, opts ...SubOption) error {
+	if err := e.checkHandler(h); err != nil {
+		return err
+	}
+	return s.Subscribe(e.topic, h, opts...)
+}
+
+// AddHandler 注册RPC服务
+func (s *Service) AddHandler(handles ...interface{}) {
+	opts := []server.HandlerOption{
+		server.InternalHandler(true),
+	}
+
+	for _, h := range handles {
+		if err := micro.RegisterHandler(s.Server(), h, opts...); err != nil {
+			log.Error("RegisterHandler Error: %s", err.Error())
+		}
+	}
+}
+
+// AddMetadata 增加meta信息 (服务启动前设置, 运行中变更使用 SetMetadata)
+func (s *Service) AddMetadata(meta map[string]string) {
+	for k, v := range meta {
+		s.Server().Options().Metadata[k] = v
+	}
+}
+
+// CallCtx 通过名称调用RPC
+// 	@name 服务名称
+// 	@method rpc方法名称. 即 serviceName.rpcName
+// 	@in 请求参数
+// 	@out 返回数据
+func (s *Service) CallCtx(ctx context.Context, srvName string, method string, in interface{}, out interface{}, opts ...client.CallOption) error {
+	req := s.Client().NewRequest(srvName, method, in)
+	return s.Client().Call(ctx, req, out, opts...)
+}
+
+// CallNode 调用指定服务节点的RPC
+// 	@nodeId 指定节点ID
+func (s *Service) CallNode(ctx context.Context, srvName string, method string, in interface{}, out interface{}, nodeId ...string) error {
+	var opts []client.CallOption
+	if len(nodeId) > 0 {
+		opts = append(opts, FilterServiceNode(srvName, nodeId[0]))
+	}
+	return s.CallCtx(ctx, srvName, method, in, out, opts...)
+}
+
+// NewService 创建服务实例 (同一进程的多个服务共用进程参数, 由首个服务解析)
+func NewService(name string, version string, extra ...[]cli.Flag) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Service{
+		ctx:       ctx,
+		cancel:    cancel,
+		publisher: make(map[string]micro.Publisher),
+		subOpts:   make(map[string]*SubOptions),
+		metadata:  make(map[string]string),
+		cronJobs:  make(map[string]*cronEntry),
+	}
+
+	// 合并flags
+	var flags = append([]cli.Flag{}, defaultFlags...)
+	for _, ex := range extra {
+		flags = append(flags, ex...)
+	}
+
+	// 进程参数 (Opts 等) 仅由首个服务解析, 避免运行中的服务读取时被改写
+	if !atomic.CompareAndSwapInt32(&parsedFlags, 0, 1) {
+		flags = withoutDestination(flags)
+	}
+
 	// 创建服务
-	APP().newService(name, version, flags)
+	s.newService(name, version, flags)
+
+	// 服务发现注册检查
+	s.AddHealthCheck(RegistryHealthCheck, s.Registered)
 
 	// 关闭事件
-	_ = AddSub("cancel", func(_ context.Context, c *pb.Cancel) error {
-		if c.Name != Name() || (c.NodeId != "" && c.NodeId != Id()) {
+	_ = s.SubEvent(CancelEvent, func(_ context.Context, c *pb.Cancel) error {
+		if c.Name != s.Name() || (c.NodeId != "" && c.NodeId != s.Id()) {
 			return nil
 		}
 
-		log.Debug("[ServiceCancel] service [%s][%s] is being stopped ...", Name(), Id())
+		log.Debug("[ServiceCancel] service [%s][%s] is being stopped ...", s.Name(), s.Id())
 
 		// 停止服务
-		APP().Cancel()
+		s.Close()
 
 		return nil
 	})
+
+	return s
+}
+
+// New 创建默认服务 (已存在时忽略并记录警告)
+func New(name string, version string, extra ...[]cli.Flag) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultService != nil {
+		log.Warn("[Service] default service [%s] already exists, ignore new service [%s]", defaultService.Name(), name)
+		return
+	}
+
+	defaultService = NewService(name, version, extra...)
 }
 
 // 启动服务
 func Init(opts ...micro.Option) {
-	APP().initService(opts...)
+	Default().Init(opts...)
 }
 
 // Service 获取服务对象
 func Srv() micro.Service {
-	return APP().srv
+	return Default().Srv()
 }
 
 // Client 获取客户端
 func Client() client.Client {
-	return Srv().Client()
+	return Default().Client()
 }
 
 // Server 获取服务端
 func Server() server.Server {
-	return Srv().Server()
+	return Default().Server()
 }
 
 // 启动服务
 func Run() error {
-	return Srv().Run()
+	return Default().Run()
 }
 
 // 关闭服务
 func Close() {
-	APP().Cancel()
+	Default().Close()
 }
 
 // 取消服务
 func Cancel(name string, id ...string) error {
-	in := &pb.Cancel{Name: name}
-	if len(id) > 0 {
-		in.NodeId = id[0]
-	}
-	return Pub("cancel", in)
+	return Default().CancelNode(name, id...)
 }
 
 // Version 获取服务版本
 func Version() string {
-	return Server().Options().Version
+	return Default().Version()
 }
 
 // Id 获取服务UUID
 func Id() string {
-	return Server().Options().Id
+	return Default().Id()
 }
 
 // Name 获取服务名称
 func Name() string {
-	return Server().Options().Name
+	return Default().Name()
 }
 
 // NameId 获取服务节点ID
 func NameId() string {
-	return fmt.Sprintf("%s-%s", Name(), Id())
+	return Default().NameId()
 }
 
 // 获取服务发现注册信息
 func Registry() registry.Registry {
-	return APP().Service().Options().Registry
+	return Default().Registry()
 }
 
 // 获取服务节点列表
 func GetServices(name string) []*registry.Service {
-	res, _ := Registry().GetService(name)
-	return res
+	return Default().GetServices(name)
 }
 
 // 注册发布者
 func AddPub(names ...string) {
-	for _, name := range names {
-		APP().publisher[name] = micro.NewEvent(name, Client())
-	}
+	Default().AddPub(names...)
 }
 
 // 获取发布者
 func GetPub(name string) micro.Publisher {
-	return APP().publisher[name]
+	return Default().GetPub(name)
 }
 
 // 发布消息
 func Pub(name string, msg interface{}, opts ...client.PublishOption) error {
-	return PubCtx(context.TODO(), name, msg, opts...)
+	return Default().Pub(name, msg, opts...)
 }
 func PubCtx(ctx context.Context, name string, msg interface{}, opts ...client.PublishOption) error {
-	return GetPub(name).Publish(ctx, msg, opts...)
+	return Default().PubCtx(ctx, name, msg, opts...)
 }
 
 // 注册订阅
 func AddSub(name string, h interface{}, queue ...bool) error {
-	var opts = []server.SubscriberOption{
-		server.InternalSubscriber(true),
-	}
-	if len(queue) > 0 && queue[0] {
-		opts = append(opts, server.SubscriberQueue(name))
-	}
-
-	return micro.RegisterSubscriber(name, Server(), h, opts...)
+	return Default().AddSub(name, h, queue...)
 }
 
 func AddSubQueue(name string, h interface{}) error {
-	return AddSub(name, h, true)
+	return Default().AddSubQueue(name, h)
+}
+
+// 发布事件
+func PubEvent(ctx context.Context, e *Event, msg proto.Message, opts ...client.PublishOption) error {
+	return Default().PubEvent(ctx, e, msg, opts...)
+}
+
+// 订阅事件
+func SubEvent(e *Event, h interface{}, opts ...SubOption) error {
+	return Default().SubEvent(e, h, opts...)
 }
 
 // 注册RPC服务
 func AddHandler(handles ...interface{}) {
-	opts := []server.HandlerOption{
-		server.InternalHandler(true),
-	}
-
-	for _, h := range handles {
-		if err := micro.RegisterHandler(Server(), h, opts...); err != nil {
-			log.Error("RegisterHandler Error: %s", err.Error())
-		}
-	}
+	Default().AddHandler(handles...)
 }
 
 // 增加meta信息
 func AddMetadata(meta map[string]string) {
-	for k, v := range meta {
-		Server().Options().Metadata[k] = v
-	}
+	Default().AddMetadata(meta)
 }
 
 // Call 通过名称调用RPC
@@ -263,8 +502,7 @@ func AddMetadata(meta map[string]string) {
 // 	@out 返回数据
 // 	@nodeId 指定节点ID
 func CallCtx(ctx context.Context, srvName string, method string, in interface{}, out interface{}, opts ...client.CallOption) error {
-	req := Client().NewRequest(srvName, method, in)
-	return Client().Call(ctx, req, out, opts...)
+	return Default().CallCtx(ctx, srvName, method, in, out, opts...)
 }
 
 // CallCtx 通过名称调用RPC
@@ -274,9 +512,5 @@ func CallCtx(ctx context.Context, srvName string, method string, in interface{},
 // 	@out 返回数据
 // 	@nodeId 指定节点ID
 func CallNode(ctx context.Context, srvName string, method string, in interface{}, out interface{}, nodeId ...string) error {
-	var opts []client.CallOption
-	if len(nodeId) > 0 {
-		opts = append(opts, FilterServiceNode(srvName, nodeId[0]))
-	}
-	return CallCtx(ctx, srvName, method, in, out, opts...)
+	return Default().CallNode(ctx, srvName, method, in, out, nodeId...)
 }
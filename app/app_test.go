@@ -0,0 +1,20 @@
+package app
+
+import (
+	"testing"
+)
+
+// 兼容旧版 APP().Cancel() 关闭服务
+func TestService_Cancel(t *testing.T) {
+	s := NewService("test.cancel", "latest")
+	if s.Service() != s.Srv() {
+		t.Fatal("service mismatch")
+	}
+
+	s.Cancel()
+	select {
+	case <-s.ctx.Done():
+	default:
+		t.Fatal("service not cancelled")
+	}
+}
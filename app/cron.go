@@ -0,0 +1,309 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/bsm/redislock"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/robfig/cron/v3"
+	"time"
+
+	rds "github.com/cbwfree/micro-game/store/redis"
+)
+
+const (
+	DefaultCronTimeout = 10 * time.Minute // 默认任务锁定时间 (同一时刻任务仅执行一次)
+	DefaultCronHistory = 100              // 默认保留执行记录数量
+)
+
+// 定时任务表达式解析 (秒可选, 支持 @every, @daily 等描述符及 CRON_TZ= 前缀)
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// CronJob 定时任务
+type CronJob func(ctx context.Context) error
+
+// CronRun 定时任务执行记录
+type CronRun struct {
+	Job   string    `json:"job"`
+	Node  string    `json:"node"` // 执行节点
+	Tick  time.Time `json:"tick"` // 计划执行时间
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Error string    `json:"error,omitempty"`
+}
+
+type CronOption func(o *CronOptions)
+
+type CronOptions struct {
+	Local   bool          // 每个节点均执行 (不加锁)
+	Timeout time.Duration // 任务锁定时间, 同时作为执行超时
+	History int           // 保留执行记录数量
+}
+
+// CronLocal 每个节点均执行
+func CronLocal() CronOption {
+	return func(o *CronOptions) {
+		o.Local = true
+	}
+}
+
+// CronTimeout 任务锁定及执行超时时间
+func CronTimeout(d time.Duration) CronOption {
+	return func(o *CronOptions) {
+		o.Timeout = d
+	}
+}
+
+// CronHistory 保留执行记录数量
+func CronHistory(n int) CronOption {
+	return func(o *CronOptions) {
+		o.History = n
+	}
+}
+
+// 已注册的定时任务
+type cronEntry struct {
+	name     string
+	spec     string
+	schedule cron.Schedule
+	job      CronJob
+	opts     *CronOptions
+	id       cron.EntryID
+}
+
+// 定时任务时区 (由 --cron_timezone 参数设置, 解析参数后使用)
+func cronLocation() (*time.Location, error) {
+	if Opts.CronTimezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(Opts.CronTimezone)
+	if err != nil {
+		return nil, errors.Invalid("invalid cron timezone %s: %s", Opts.CronTimezone, err)
+	}
+	return loc, nil
+}
+
+// AddCron 注册定时任务 (服务启动前注册的任务在启动时按 --cron_timezone 时区调度)
+// 	默认同一计划时间仅由一个节点执行 (Redis锁), 执行记录保存在Redis中
+func (s *Service) AddCron(name string, spec string, job CronJob, opts ...CronOption) error {
+	o := &CronOptions{
+		Timeout: DefaultCronTimeout,
+		History: DefaultCronHistory,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return errors.Invalid("invalid cron %s spec %s: %s", name, spec, err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.cronJobs[name]; ok {
+		return errors.Exists("cron %s already exists", name)
+	}
+
+	e := &cronEntry{name: name, spec: spec, schedule: schedule, job: job, opts: o}
+	s.cronJobs[name] = e
+	if s.cron != nil {
+		s.scheduleCron(e)
+	}
+
+	return nil
+}
+
+// 调度定时任务 (调用时需持有锁)
+func (s *Service) scheduleCron(e *cronEntry) {
+	e.id = s.cron.Schedule(e.schedule, cron.FuncJob(func() {
+		s.runCron(e)
+	}))
+}
+
+// RemoveCron 注销定时任务
+func (s *Service) RemoveCron(name string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if e, ok := s.cronJobs[name]; ok {
+		if s.cron != nil {
+			s.cron.Remove(e.id)
+		}
+		delete(s.cronJobs, name)
+	}
+}
+
+// CronNext 获取定时任务下次执行时间
+func (s *Service) CronNext(name string) (time.Time, error) {
+	s.RLock()
+	e, ok := s.cronJobs[name]
+	c := s.cron
+	s.RUnlock()
+	if !ok {
+		return time.Time{}, errors.NotFound("cron %s not found", name)
+	}
+
+	if c != nil {
+		if next := c.Entry(e.id).Next; !next.IsZero() {
+			return next, nil
+		}
+	}
+
+	// 未启动时按当前时间计算
+	loc, err := cronLocation()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return e.schedule.Next(time.Now().In(loc)), nil
+}
+
+// CronRuns 获取定时任务执行记录 (最新的在前)
+func (s *Service) CronRuns(ctx context.Context, name string, limit int) ([]*CronRun, error) {
+	if rds.Client() == nil {
+		return nil, errors.Unavailable("redis is not connected")
+	}
+	if limit <= 0 {
+		limit = DefaultCronHistory
+	}
+
+	values, err := rds.Client().LRange(ctx, s.cronHistoryKey(name), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]*CronRun, 0, len(values))
+	for _, v := range values {
+		run := new(CronRun)
+		if err := json.Unmarshal([]byte(v), run); err != nil {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func (s *Service) cronHistoryKey(name string) string {
+	return fmt.Sprintf("cron:%s:%s:history", s.Name(), name)
+}
+
+// 执行定时任务
+func (s *Service) runCron(e *cronEntry) {
+	s.RLock()
+	id, c := e.id, s.cron
+	s.RUnlock()
+
+	tick := c.Entry(id).Prev
+	if tick.IsZero() {
+		tick = time.Now().Truncate(time.Second)
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, e.opts.Timeout)
+	defer cancel()
+
+	// 同一计划时间加锁 (不释放, 到期自动删除, 避免时钟偏差导致重复执行)
+	if !e.opts.Local {
+		if rds.Client() == nil {
+			log.Warn("[Cron] job [%s] skipped: redis is not connected", e.name)
+			return
+		}
+		index := fmt.Sprintf("%s:%s:%d", s.Name(), e.name, tick.Unix())
+		if _, err := rds.LockBackoff(ctx, "CRON", index, e.opts.Timeout, 0); err != nil {
+			if err != redislock.ErrNotObtained {
+				log.Warn("[Cron] job [%s] lock error: %s", e.name, err)
+			}
+			return
+		}
+	}
+
+	run := &CronRun{Job: e.name, Node: s.Id(), Tick: tick, Start: time.Now()}
+	if err := s.callCron(ctx, e); err != nil {
+		run.Error = err.Error()
+		log.Error("[Cron] job [%s] tick [%s] error: %s", e.name, tick.Format(time.RFC3339), err)
+	}
+	run.End = time.Now()
+
+	s.saveCronRun(e, run)
+}
+
+// 执行任务 (捕获异常)
+func (s *Service) callCron(ctx context.Context, e *cronEntry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Server("cron %s panic: %v", e.name, r)
+		}
+	}()
+	return e.job(ctx)
+}
+
+// 保存执行记录
+func (s *Service) saveCronRun(e *cronEntry, run *CronRun) {
+	if rds.Client() == nil || e.opts.History <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+	pipe := rds.Client().TxPipeline()
+	pipe.LPush(ctx, s.cronHistoryKey(e.name), data)
+	pipe.LTrim(ctx, s.cronHistoryKey(e.name), 0, int64(e.opts.History-1))
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Warn("[Cron] save job [%s] history error: %s", e.name, err)
+	}
+}
+
+// 启动定时任务调度 (解析参数后创建调度器, 调度已注册的任务)
+func (s *Service) startCron() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.cron == nil {
+		loc, err := cronLocation()
+		if err != nil {
+			return err
+		}
+		s.cron = cron.New(cron.WithParser(cronParser), cron.WithLocation(loc))
+		for _, e := range s.cronJobs {
+			s.scheduleCron(e)
+		}
+	}
+	s.cron.Start()
+
+	return nil
+}
+
+// 停止定时任务调度 (等待执行中的任务完成)
+func (s *Service) stopCron() error {
+	s.RLock()
+	c := s.cron
+	s.RUnlock()
+
+	if c != nil {
+		<-c.Stop().Done()
+	}
+	return nil
+}
+
+// 注册定时任务
+func AddCron(name string, spec string, job CronJob, opts ...CronOption) error {
+	return Default().AddCron(name, spec, job, opts...)
+}
+
+// 注销定时任务
+func RemoveCron(name string) {
+	Default().RemoveCron(name)
+}
+
+// 获取定时任务执行记录
+func CronRuns(ctx context.Context, name string, limit int) ([]*CronRun, error) {
+	return Default().CronRuns(ctx, name, limit)
+}
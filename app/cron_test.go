@@ -0,0 +1,41 @@
+package app
+
+import (
+	"context"
+	"testing"
+)
+
+// 解析参数前注册的任务在服务启动时使用参数设置的时区
+func TestService_CronTimezone(t *testing.T) {
+	s := NewService("test.cron", "latest")
+	defer s.RemoveHealthCheck(RegistryHealthCheck)
+
+	if err := s.AddCron("tz", "0 0 8 * * *", func(context.Context) error { return nil }, CronLocal()); err != nil {
+		t.Fatal(err)
+	}
+
+	tz := Opts.CronTimezone
+	Opts.CronTimezone = "Asia/Shanghai"
+	defer func() { Opts.CronTimezone = tz }()
+
+	if err := s.startCron(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.stopCron()
+
+	next, err := s.CronNext("tz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Location().String() != "Asia/Shanghai" || next.Hour() != 8 {
+		t.Fatalf("next run %s", next)
+	}
+
+	// 无效时区时启动失败
+	Opts.CronTimezone = "Invalid/Zone"
+	s2 := NewService("test.cron", "latest")
+	defer s2.RemoveHealthCheck(RegistryHealthCheck)
+	if err := s2.startCron(); err == nil {
+		t.Fatal("expected invalid timezone error")
+	}
+}
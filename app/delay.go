@@ -0,0 +1,134 @@
+package app
+
+import (
+	"context"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/codec/bytes"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"time"
+
+	rds "github.com/cbwfree/micro-game/store/redis"
+)
+
+const (
+	DelaySchedulerName = "app"                  // 延迟消息调度器名称 (所有服务共用同一队列)
+	DelayContentType   = "application/protobuf" // 延迟消息编码类型
+	DelayMaxAttempts   = 10                     // 延迟消息最大投递次数 (未被订阅者确认时)
+	MetaDelayId        = "Delay-Id"             // 延迟消息ID (订阅者处理完成后确认)
+)
+
+// 延迟消息调度器 (使用默认Redis存储, 由订阅者处理完成后确认)
+func (s *Service) delay() *rds.Scheduler {
+	s.delayOnce.Do(func() {
+		s.scheduler = rds.NewScheduler(rds.S(), DelaySchedulerName, s.deliverDelay,
+			rds.WithDelayManualAck(),
+			rds.WithDelayMaxAttempts(DelayMaxAttempts),
+		)
+	})
+	return s.scheduler
+}
+
+// 启动延迟消息投递 (已连接Redis的服务节点均参与投递)
+func (s *Service) startDelay() error {
+	if rds.Client() != nil {
+		s.delay().Start()
+	}
+	return nil
+}
+
+// 停止延迟消息投递
+func (s *Service) stopDelay() error {
+	s.delay().Stop()
+	return nil
+}
+
+// 投递到期消息 (直接发布消息数据, 投递节点无需注册消息类型)
+func (s *Service) deliverDelay(ctx context.Context, dm *rds.DelayMessage) error {
+	ct := dm.ContentType
+	if ct == "" {
+		ct = DelayContentType
+	}
+
+	// 使用延迟消息ID (消息头中指定时使用原消息ID), 重复投递时订阅者可幂等处理
+	ctx = metadata.Set(newMessageContext(ctx, dm.Id), MetaDelayId, dm.Id)
+	for k, v := range dm.Header {
+		if v != "" {
+			ctx = metadata.Set(ctx, k, v)
+		}
+	}
+	return s.Client().Publish(ctx, s.Client().NewMessage(dm.Topic, &bytes.Frame{Data: dm.Body}, client.WithMessageContentType(ct)))
+}
+
+// 确认延迟消息 (订阅者处理完成后调用)
+func (s *Service) ackDelay(msg server.Message) {
+	id := msg.Header()[MetaDelayId]
+	if id == "" || rds.Client() == nil {
+		return
+	}
+	if err := s.delay().Ack(context.Background(), id); err != nil {
+		log.Warn("[Delay] ack message [%s] topic [%s] error: %s", id, msg.Topic(), err)
+	}
+}
+
+// PubDelay 延迟发布消息, 返回消息ID (用于取消)
+func (s *Service) PubDelay(ctx context.Context, name string, msg proto.Message, delay time.Duration) (string, error) {
+	return s.PubAt(ctx, name, msg, time.Now().Add(delay))
+}
+
+// PubAt 定时发布消息, 返回消息ID (用于取消)
+// 	消息保存在Redis中, 到期后由任一服务节点发布, 订阅者处理完成 (成功或发布死信) 后确认
+// 	未确认的消息在租约到期后重新发布 (至少投递一次), 订阅者应使用队列订阅并幂等处理
+func (s *Service) PubAt(ctx context.Context, name string, msg proto.Message, at time.Time) (string, error) {
+	if e, ok := GetEvent(name); ok {
+		if err := e.check(msg); err != nil {
+			return "", err
+		}
+	}
+	if s.GetPub(name) == nil {
+		return "", errors.NotFound("publisher %s not registered", name)
+	}
+
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+
+	dm := &rds.DelayMessage{
+		Id:          uuid.New().String(),
+		Topic:       name,
+		Type:        proto.MessageName(msg),
+		ContentType: DelayContentType,
+		Body:        body,
+		Due:         at.UnixNano() / 1e6,
+	}
+	if err := s.delay().Add(ctx, dm); err != nil {
+		return "", err
+	}
+
+	return dm.Id, nil
+}
+
+// CancelDelay 取消延迟消息, 返回消息是否存在 (已投递的消息不存在)
+func (s *Service) CancelDelay(ctx context.Context, id string) (bool, error) {
+	return s.delay().Cancel(ctx, id)
+}
+
+// 延迟发布消息
+func PubDelay(ctx context.Context, name string, msg proto.Message, delay time.Duration) (string, error) {
+	return Default().PubDelay(ctx, name, msg, delay)
+}
+
+// 定时发布消息
+func PubAt(ctx context.Context, name string, msg proto.Message, at time.Time) (string, error) {
+	return Default().PubAt(ctx, name, msg, at)
+}
+
+// 取消延迟消息
+func CancelDelay(ctx context.Context, id string) (bool, error) {
+	return Default().CancelDelay(ctx, id)
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/golang/protobuf/proto"
+	"reflect"
+	"sync"
+)
+
+var (
+	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// 已定义的事件
+var events = struct {
+	sync.RWMutex
+	m map[string]*Event
+}{m: make(map[string]*Event)}
+
+// Event 事件 (主题绑定消息类型)
+// 	发布时检查消息类型, 订阅时检查处理函数签名 func(context.Context, *Message) error
+type Event struct {
+	topic string
+	typ   reflect.Type
+}
+
+// Topic 事件主题
+func (e *Event) Topic() string {
+	return e.topic
+}
+
+// New 创建事件消息
+func (e *Event) New() proto.Message {
+	return reflect.New(e.typ.Elem()).Interface().(proto.Message)
+}
+
+// 检查消息类型
+func (e *Event) check(msg interface{}) error {
+	if reflect.TypeOf(msg) != e.typ {
+		return errors.Invalid("event %s message type %T, expected %s", e.topic, msg, e.typ)
+	}
+	return nil
+}
+
+// 检查订阅处理函数签名
+func (e *Event) checkHandler(h interface{}) error {
+	typ := reflect.TypeOf(h)
+	if typ == nil || typ.Kind() != reflect.Func {
+		return errors.Invalid("event %s handler %T is not a function", e.topic, h)
+	}
+	if typ.NumIn() != 2 || typ.In(0) != typeOfContext || typ.In(1) != e.typ ||
+		typ.NumOut() != 1 || typ.Out(0) != typeOfError {
+		return errors.Invalid("event %s handler %s, expected func(context.Context, %s) error", e.topic, typ, e.typ)
+	}
+	return nil
+}
+
+func isFunc(h interface{}) bool {
+	return reflect.ValueOf(h).Kind() == reflect.Func
+}
+
+// NewEvent 定义事件 (同一主题只能绑定一种消息类型, 否则 panic)
+func NewEvent(topic string, msg proto.Message) *Event {
+	typ := reflect.TypeOf(msg)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("event %s message must be a pointer to proto.Message", topic))
+	}
+
+	events.Lock()
+	defer events.Unlock()
+
+	if e, ok := events.m[topic]; ok {
+		if e.typ != typ {
+			panic(fmt.Sprintf("event %s already defined with %s", topic, e.typ))
+		}
+		return e
+	}
+
+	e := &Event{topic: topic, typ: typ}
+	events.m[topic] = e
+	return e
+}
+
+// GetEvent 获取已定义的事件
+func GetEvent(topic string) (*Event, bool) {
+	events.RLock()
+	defer events.RUnlock()
+
+	e, ok := events.m[topic]
+	return e, ok
+}
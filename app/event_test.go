@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/pb"
+	"testing"
+)
+
+func TestNewEvent(t *testing.T) {
+	e := NewEvent("test.event", new(pb.Push))
+	if NewEvent("test.event", new(pb.Push)) != e {
+		t.Fatal("same event expected")
+	}
+	if ev, ok := GetEvent("test.event"); !ok || ev != e {
+		t.Fatal("event not found")
+	}
+	if _, ok := e.New().(*pb.Push); !ok {
+		t.Fatalf("new message %T", e.New())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("redefine event with other type should panic")
+		}
+	}()
+	NewEvent("test.event", new(pb.Cancel))
+}
+
+func TestEvent_Check(t *testing.T) {
+	e := NewEvent("test.check", new(pb.Push))
+
+	if err := e.check(new(pb.Push)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.check(new(pb.Cancel)); err == nil {
+		t.Fatal("check wrong message type")
+	}
+
+	if err := e.checkHandler(func(context.Context, *pb.Push) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range []interface{}{
+		nil,
+		new(pb.Push),
+		func(context.Context, *pb.Cancel) error { return nil },
+		func(*pb.Push) error { return nil },
+		func(context.Context, *pb.Push) {},
+	} {
+		if err := e.checkHandler(h); err == nil {
+			t.Fatalf("check wrong handler %T", h)
+		}
+	}
+}
+
+func TestService_Pub(t *testing.T) {
+	s := NewService("test.pub", "latest")
+	defer s.RemoveHealthCheck(RegistryHealthCheck)
+
+	err := s.Pub("test.unknown", new(pb.Push))
+	if errors.Parse(err).Code != errors.CodeNotFound {
+		t.Fatalf("pub unknown topic: %v", err)
+	}
+
+	err = s.PubEvent(context.TODO(), CancelEvent, new(pb.Push))
+	if errors.Parse(err).Code != errors.CodeInvalid {
+		t.Fatalf("pub wrong message type: %v", err)
+	}
+	if s.GetPub(CancelEvent.Topic()) == nil {
+		t.Fatal("event publisher not registered")
+	}
+
+	if err := s.SubEvent(CancelEvent, func(context.Context, *pb.Push) error { return nil }); err == nil {
+		t.Fatal("subscribe wrong handler")
+	}
+}
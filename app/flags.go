@@ -2,6 +2,7 @@ package app
 
 import (
 	"github.com/micro/cli/v2"
+	"reflect"
 )
 
 var (
@@ -9,6 +10,13 @@ var (
 		Dev    bool   // 是否开发模式
 		PsAddr string // gops分析
 		Lang   string // 设置语言环境
+
+		TraceExporter string // 链路跟踪导出器 (otlp, memory)
+		TraceAddress  string // 链路跟踪导出地址 (OTLP/HTTP)
+		MetricsAddr   string // 监控指标导出地址
+		HealthAddr    string // 健康检查地址
+		Standalone    bool   // 单进程模式
+		CronTimezone  string // 定时任务时区
 	})
 
 	defaultFlags = []cli.Flag{
@@ -31,6 +39,37 @@ var (
 			EnvVars:     []string{"GAME_LANG"},
 			Destination: &Opts.Lang,
 		},
+		&cli.StringFlag{
+			Name:        "trace_exporter",
+			Usage:       "设置链路跟踪导出器, e.g. otlp, memory",
+			EnvVars:     []string{"GAME_TRACE_EXPORTER"},
+			Destination: &Opts.TraceExporter,
+		},
+		&cli.StringFlag{
+			Name:        "trace_address",
+			Value:       "http://127.0.0.1:4318/v1/traces",
+			Usage:       "设置链路跟踪OTLP/HTTP导出地址",
+			EnvVars:     []string{"GAME_TRACE_ADDRESS"},
+			Destination: &Opts.TraceAddress,
+		},
+		&cli.StringFlag{
+			Name:        "metrics_address",
+			Usage:       "设置监控指标(Prometheus)导出地址, e.g. :9100. 为空时不启用",
+			EnvVars:     []string{"GAME_METRICS_ADDRESS"},
+			Destination: &Opts.MetricsAddr,
+		},
+		&cli.StringFlag{
+			Name:        "health_address",
+			Usage:       "设置健康检查HTTP地址 (同时启用gRPC健康检查服务), e.g. :8086. 为空时不启用",
+			EnvVars:     []string{"GAME_HEALTH_ADDRESS"},
+			Destination: &Opts.HealthAddr,
+		},
+		&cli.StringFlag{
+			Name:        "cron_timezone",
+			Usage:       "设置定时任务时区, e.g. Asia/Shanghai. 为空时使用本地时区",
+			EnvVars:     []string{"GAME_CRON_TIMEZONE"},
+			Destination: &Opts.CronTimezone,
+		},
 		&cli.StringFlag{
 			Name:    "profile",
 			Usage:   "Debug profiler for cpu and memory stats",
@@ -64,6 +103,32 @@ var (
 			EnvVars: []string{"GAME_CLIENT_POOL_TTL"},
 			Usage:   "设置客户端连接TTL. e.g 500ms, 5s, 1m. Default: 1m",
 		},
+		&cli.BoolFlag{
+			Name:        "standalone",
+			Usage:       "单进程模式, 未指定的服务发现、消息代理及传输使用内存插件, 服务端监听本地回环地址",
+			EnvVars:     []string{"GAME_STANDALONE"},
+			Destination: &Opts.Standalone,
+		},
+		&cli.StringFlag{
+			Name:    "registry",
+			EnvVars: []string{"GAME_REGISTRY"},
+			Usage:   "服务发现, e.g. etcd, mdns, memory. Default: etcd",
+		},
+		&cli.StringFlag{
+			Name:    "broker",
+			EnvVars: []string{"GAME_BROKER"},
+			Usage:   "消息代理, e.g. nats, http, memory. Default: nats",
+		},
+		&cli.StringFlag{
+			Name:    "transport",
+			EnvVars: []string{"GAME_TRANSPORT"},
+			Usage:   "传输, e.g. grpc, http, memory. Default: grpc",
+		},
+		&cli.StringFlag{
+			Name:    "transport_address",
+			EnvVars: []string{"GAME_TRANSPORT_ADDRESS"},
+			Usage:   "传输地址. 以逗号分隔",
+		},
 		&cli.StringFlag{
 			Name:    "registry_address",
 			EnvVars: []string{"GAME_REGISTRY_ADDRESS"},
@@ -103,3 +168,26 @@ var (
 		},
 	}
 )
+
+// 复制参数并去除 Destination (同一进程的其它服务不再写入进程参数)
+func withoutDestination(flags []cli.Flag) []cli.Flag {
+	var res = make([]cli.Flag, 0, len(flags))
+	for _, f := range flags {
+		v := reflect.ValueOf(f)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+			res = append(res, f)
+			continue
+		}
+		c := reflect.New(v.Elem().Type())
+		c.Elem().Set(v.Elem())
+		if d := c.Elem().FieldByName("Destination"); d.IsValid() && d.CanSet() {
+			d.Set(reflect.Zero(d.Type()))
+		}
+		if nf, ok := c.Interface().(cli.Flag); ok {
+			res = append(res, nf)
+		} else {
+			res = append(res, f)
+		}
+	}
+	return res
+}
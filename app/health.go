@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/health"
+	"github.com/cbwfree/micro-game/web"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// 健康检查路径 (HealthPath/live, HealthPath/ready)
+const HealthPath = "/health"
+
+// 服务发现注册健康检查名称 (注册名称为 registry:服务名称-节点ID)
+const RegistryHealthCheck = "registry"
+
+var healthServer *web.Server
+
+// AddHealthCheck 注册健康检查 (就绪检查时执行)
+func AddHealthCheck(name string, check health.Checker) {
+	health.Register(name, check)
+}
+
+// RemoveHealthCheck 注销健康检查
+func RemoveHealthCheck(name string) {
+	health.Unregister(name)
+}
+
+// CheckHealth 执行全部健康检查
+func CheckHealth(ctx context.Context) *health.Report {
+	return health.Check(ctx)
+}
+
+// Registered 检查当前节点是否已注册到服务发现
+func (s *Service) Registered(_ context.Context) error {
+	services, err := s.Registry().GetService(s.Name())
+	if err != nil {
+		return errors.Unavailable("registry: %s", err)
+	}
+	for _, srv := range services {
+		for _, node := range srv.Nodes {
+			if node.Id == s.NameId() {
+				return nil
+			}
+		}
+	}
+	return errors.Unavailable("service node %s is not registered", s.NameId())
+}
+
+// AddHealthCheck 注册服务的健康检查, 返回注册名称 (名称:服务名称-节点ID, 同一进程的多个服务互不覆盖)
+func (s *Service) AddHealthCheck(name string, check health.Checker) string {
+	name = s.healthName(name)
+	health.Register(name, check)
+	return name
+}
+
+// RemoveHealthCheck 注销服务的健康检查
+func (s *Service) RemoveHealthCheck(name string) {
+	health.Unregister(s.healthName(name))
+}
+
+// 服务健康检查注册名称
+func (s *Service) healthName(name string) string {
+	return name + ":" + s.NameId()
+}
+
+// 启用健康检查服务 (HTTP存活及就绪检查)
+func startHealth() error {
+	if Opts.HealthAddr == "" {
+		return nil
+	}
+
+	healthServer = web.NewServer("Health")
+	healthServer.With(
+		web.WithAddr(Opts.HealthAddr),
+		web.WithHealth(HealthPath),
+	)
+
+	return healthServer.Start()
+}
+
+// 关闭健康检查服务
+func stopHealth() error {
+	if healthServer != nil {
+		healthServer.Stop()
+		healthServer = nil
+	}
+	return nil
+}
+
+// Health gRPC健康检查服务
+// 	service 为空时检查服务整体状态, 否则检查指定名称的健康检查
+type Health struct{}
+
+func (*Health) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest, rsp *grpc_health_v1.HealthCheckResponse) error {
+	rsp.Status = grpc_health_v1.HealthCheckResponse_SERVING
+
+	if req.Service == "" {
+		if !health.Check(ctx).Up() {
+			rsp.Status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		}
+		return nil
+	}
+
+	if err := health.CheckOne(ctx, req.Service); err != nil {
+		if errors.Parse(err).Code == errors.CodeNotFound {
+			return err
+		}
+		rsp.Status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+
+	return nil
+}
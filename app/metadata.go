@@ -0,0 +1,85 @@
+package app
+
+import (
+	"github.com/micro/go-micro/v2/registry"
+	"sync/atomic"
+)
+
+// 节点meta信息
+const (
+	MetaConnections = "connections" // 当前连接数 (网关上报, 用于选择连接数最少的节点)
+)
+
+// 注册时合并动态meta信息的服务发现
+// 	服务端首次注册后缓存注册信息, 此后的meta信息变更需在注册时合并
+type metaRegistry struct {
+	registry.Registry
+	s *Service
+}
+
+func (r *metaRegistry) Register(srv *registry.Service, opts ...registry.RegisterOption) error {
+	md := r.s.dynamicMetadata()
+	if len(md) == 0 {
+		return r.Registry.Register(srv, opts...)
+	}
+
+	nameId := r.s.NameId()
+	cp := *srv
+	cp.Nodes = make([]*registry.Node, len(srv.Nodes))
+	for i, node := range srv.Nodes {
+		if node.Id != nameId {
+			cp.Nodes[i] = node
+			continue
+		}
+
+		n := *node
+		n.Metadata = make(map[string]string, len(node.Metadata)+len(md))
+		for k, v := range node.Metadata {
+			n.Metadata[k] = v
+		}
+		for k, v := range md {
+			n.Metadata[k] = v
+		}
+		cp.Nodes[i] = &n
+	}
+
+	return r.Registry.Register(&cp, opts...)
+}
+
+// 获取动态meta信息
+func (s *Service) dynamicMetadata() map[string]string {
+	s.RLock()
+	defer s.RUnlock()
+
+	if len(s.metadata) == 0 {
+		return nil
+	}
+
+	md := make(map[string]string, len(s.metadata))
+	for k, v := range s.metadata {
+		md[k] = v
+	}
+	return md
+}
+
+// SetMetadata 设置动态meta信息 (服务运行中时立即重新注册)
+func (s *Service) SetMetadata(md map[string]string) error {
+	s.Lock()
+	for k, v := range md {
+		s.metadata[k] = v
+	}
+	s.Unlock()
+
+	if atomic.LoadInt32(&s.running) == 0 {
+		return nil
+	}
+	if r, ok := s.Server().(interface{ Register() error }); ok {
+		return r.Register()
+	}
+	return nil
+}
+
+// 设置动态meta信息
+func SetMetadata(md map[string]string) error {
+	return Default().SetMetadata(md)
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"github.com/cbwfree/micro-game/utils/metrics"
+	"github.com/cbwfree/micro-game/web"
+)
+
+// 监控指标导出路径
+const MetricsPath = "/metrics"
+
+var metricsServer *web.Server
+
+// 启用监控指标及导出服务
+func startMetrics(name string) error {
+	if Opts.MetricsAddr == "" {
+		return nil
+	}
+
+	metrics.Enable(name)
+
+	metricsServer = web.NewServer("Metrics")
+	metricsServer.With(
+		web.WithAddr(Opts.MetricsAddr),
+		web.WithMetrics(MetricsPath),
+	)
+
+	return metricsServer.Start()
+}
+
+// 关闭监控指标导出服务
+func stopMetrics() error {
+	if metricsServer != nil {
+		metricsServer.Stop()
+		metricsServer = nil
+	}
+	return nil
+}
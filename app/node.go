@@ -2,7 +2,10 @@ package app
 
 import (
 	"fmt"
+	"github.com/cbwfree/micro-game/utils/dtype"
+	"github.com/cbwfree/micro-game/utils/errors"
 	"github.com/cbwfree/micro-game/utils/tool"
+	"github.com/micro/go-micro/v2/client/selector"
 	"math/rand"
 	"strings"
 )
@@ -172,3 +175,53 @@ func GetRandomNodeId(srvName string) (string, error) {
 
 	return node.UUID, nil
 }
+
+// GetHashServiceNode 按键一致性哈希获取服务节点 (与 FilterServiceHash 选择的节点一致)
+func GetHashServiceNode(srvName string, key interface{}) (*ServiceNode, error) {
+	nodes := GetServiceNodes(srvName)
+	if len(nodes) == 0 {
+		return nil, errors.NotFound("not found %s service node", srvName)
+	}
+
+	var nodeIds = make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		nodeIds = append(nodeIds, n.Id)
+	}
+
+	nodeId := getServiceRing(srvName, nodeIds).Get(dtype.ParseStr(key))
+	for _, n := range nodes {
+		if n.Id == nodeId {
+			return n, nil
+		}
+	}
+	return nil, errors.NotFound("not found %s service node", srvName)
+}
+
+// SelectServiceNode 按过滤条件选择服务节点 (多个节点时随机选择)
+// 	如选择指定类型连接数最少的网关: SelectServiceNode("gate", FilterMetadata("type", "tcp"), FilterLeastConn())
+func SelectServiceNode(srvName string, filters ...selector.Filter) (*ServiceNode, error) {
+	services := GetServices(srvName)
+	for _, filter := range filters {
+		services = filter(services)
+	}
+
+	prefix := fmt.Sprintf("%s-", srvName)
+
+	var nodes []*ServiceNode
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			nodes = append(nodes, &ServiceNode{
+				UUID:     strings.Replace(n.Id, prefix, "", 1),
+				Id:       n.Id,
+				Version:  s.Version,
+				Address:  n.Address,
+				Metadata: n.Metadata,
+			})
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil, errors.NotFound("not found %s service node", srvName)
+	}
+	return nodes[rand.Intn(len(nodes))], nil
+}
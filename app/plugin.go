@@ -0,0 +1,249 @@
+package app
+
+import (
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/config/cmd"
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/transport"
+	"reflect"
+	"sync"
+
+	bmemory "github.com/micro/go-micro/v2/broker/memory"
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+	tgrpc "github.com/micro/go-micro/v2/transport/grpc"
+	tmemory "github.com/micro/go-micro/v2/transport/memory"
+)
+
+// 默认插件 (可通过 --registry, --broker, --transport 参数选择)
+const (
+	DefaultRegistry  = "etcd"
+	DefaultBroker    = "nats"
+	DefaultTransport = "grpc"
+	MemoryPlugin     = "memory" // 单进程模式使用的内存插件
+)
+
+// 单进程模式的服务端监听地址 (grpc 客户端及服务端不使用 transport 插件, 仍监听本地回环端口)
+const StandaloneAddress = "127.0.0.1:0"
+
+// 进程内共享的内存插件 (同一进程内的多个服务共用, 以便相互发现及订阅消息)
+var (
+	memRegistry  registry.Registry
+	memBroker    broker.Broker
+	memTransport transport.Transport
+	memOnce      sync.Once
+)
+
+func initMemory() {
+	memOnce.Do(func() {
+		memRegistry = &memoryRegistry{Registry: rmemory.NewRegistry()}
+		memBroker = &memoryBroker{Broker: bmemory.NewBroker()}
+		memTransport = tmemory.NewTransport()
+	})
+}
+
+// 内存服务发现 (注册已存在的节点时更新meta信息, 与 etcd 等保持一致)
+// 更新时先注销再注册, 期间阻塞查询, 并忽略节点仍存在的注销事件, 避免查询不到节点
+type memoryRegistry struct {
+	registry.Registry
+	sync.RWMutex
+}
+
+func (r *memoryRegistry) Register(s *registry.Service, opts ...registry.RegisterOption) error {
+	r.Lock()
+	defer r.Unlock()
+
+	services, _ := r.Registry.GetService(s.Name)
+	for _, srv := range services {
+		if srv.Version != s.Version {
+			continue
+		}
+		for _, old := range srv.Nodes {
+			for _, node := range s.Nodes {
+				if node.Id == old.Id && !reflect.DeepEqual(node.Metadata, old.Metadata) {
+					_ = r.Registry.Deregister(&registry.Service{Name: s.Name, Version: s.Version, Nodes: []*registry.Node{old}})
+				}
+			}
+		}
+	}
+	return r.Registry.Register(s, opts...)
+}
+
+func (r *memoryRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.Registry.GetService(name, opts...)
+}
+
+func (r *memoryRegistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
+	r.RLock()
+	defer r.RUnlock()
+	return r.Registry.ListServices(opts...)
+}
+
+func (r *memoryRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
+	w, err := r.Registry.Watch(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &memoryWatcher{Watcher: w, r: r}, nil
+}
+
+// 节点是否已注册
+func (r *memoryRegistry) hasNode(name, version, id string) bool {
+	services, _ := r.GetService(name)
+	for _, srv := range services {
+		if srv.Version != version {
+			continue
+		}
+		for _, node := range srv.Nodes {
+			if node.Id == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 内存服务发现监听 (忽略更新meta信息时产生的注销事件)
+type memoryWatcher struct {
+	registry.Watcher
+	r *memoryRegistry
+}
+
+func (w *memoryWatcher) Next() (*registry.Result, error) {
+	for {
+		res, err := w.Watcher.Next()
+		if err != nil || res.Action != "delete" || res.Service == nil || len(res.Service.Nodes) == 0 {
+			return res, err
+		}
+
+		var nodes []*registry.Node
+		for _, node := range res.Service.Nodes {
+			if !w.r.hasNode(res.Service.Name, res.Service.Version, node.Id) {
+				nodes = append(nodes, node)
+			}
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+
+		srv := *res.Service
+		srv.Nodes = nodes
+		return &registry.Result{Action: res.Action, Service: &srv}, nil
+	}
+}
+
+// 内存消息代理 (多个服务共用, 最后一个服务断开时才断开连接)
+type memoryBroker struct {
+	broker.Broker
+	sync.Mutex
+	conns int
+}
+
+func (b *memoryBroker) Connect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if err := b.Broker.Connect(); err != nil {
+		return err
+	}
+	b.conns++
+	return nil
+}
+
+func (b *memoryBroker) Disconnect() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.conns > 0 {
+		b.conns--
+	}
+	if b.conns > 0 {
+		return nil
+	}
+	return b.Broker.Disconnect()
+}
+
+// MemoryRegistry 进程内共享的内存服务发现
+func MemoryRegistry() registry.Registry {
+	initMemory()
+	return memRegistry
+}
+
+// MemoryBroker 进程内共享的内存消息代理
+func MemoryBroker() broker.Broker {
+	initMemory()
+	return memBroker
+}
+
+// MemoryTransport 进程内共享的内存传输 (仅 mucp 客户端及服务端使用)
+func MemoryTransport() transport.Transport {
+	initMemory()
+	return memTransport
+}
+
+// Standalone 单进程模式 (内存服务发现、消息代理及传输), 可作为 Init 参数使用
+// 	服务端未指定监听地址时监听本地回环地址 (StandaloneAddress)
+func Standalone() micro.Option {
+	return func(o *micro.Options) {
+		micro.Registry(MemoryRegistry())(o)
+		micro.Broker(MemoryBroker())(o)
+		micro.Transport(MemoryTransport())(o)
+		standaloneAddress(o.Server)
+	}
+}
+
+// 单进程模式服务端监听本地回环地址 (未指定监听地址时)
+func standaloneAddress(srv server.Server) {
+	if srv.Options().Address == server.DefaultAddress {
+		_ = srv.Init(server.Address(StandaloneAddress))
+	}
+}
+
+// 插件命令参数 (内存插件替换为进程内共享实例)
+func pluginCmdOptions() []cmd.Option {
+	return []cmd.Option{
+		cmd.NewRegistry(MemoryPlugin, func(...registry.Option) registry.Registry { return MemoryRegistry() }),
+		cmd.NewBroker(MemoryPlugin, func(...broker.Option) broker.Broker { return MemoryBroker() }),
+		cmd.NewTransport(MemoryPlugin, func(...transport.Option) transport.Transport { return MemoryTransport() }),
+		cmd.NewTransport(DefaultTransport, func(opts ...transport.Option) transport.Transport {
+			return tgrpc.NewTransport(append([]transport.Option{transport.Secure(true)}, opts...)...)
+		}),
+	}
+}
+
+// 解析参数前处理: 单进程模式时未指定的插件使用内存插件
+func (s *Service) pluginBefore(before cli.BeforeFunc) cli.BeforeFunc {
+	return func(ctx *cli.Context) error {
+		if Opts.Standalone {
+			for _, name := range []string{"registry", "broker", "transport"} {
+				if !ctx.IsSet(name) {
+					if err := ctx.Set(name, MemoryPlugin); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if before != nil {
+			if err := before(ctx); err != nil {
+				return err
+			}
+		}
+
+		// 服务端注册时合并动态meta信息
+		opts := s.srv.Options()
+		if Opts.Standalone {
+			standaloneAddress(opts.Server)
+		}
+		if err := opts.Server.Init(server.Registry(&metaRegistry{Registry: opts.Registry, s: s})); err != nil {
+			return err
+		}
+
+		// 替换消息代理后, 使用当前服务发现
+		return opts.Broker.Init(broker.Registry(opts.Registry))
+	}
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/config/cmd"
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/transport"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	rmemory "github.com/micro/go-micro/v2/registry/memory"
+)
+
+// 更新节点meta信息期间, 查询及监听均不会丢失节点
+func TestMemoryRegistry_Update(t *testing.T) {
+	r := &memoryRegistry{Registry: rmemory.NewRegistry()}
+	node := func(n int) *registry.Service {
+		return &registry.Service{Name: "test.registry", Version: "latest", Nodes: []*registry.Node{
+			{Id: "node-1", Address: "127.0.0.1:1", Metadata: map[string]string{"n": strconv.Itoa(n)}},
+		}}
+	}
+	if err := r.Register(node(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := r.Watch(registry.WatchService("test.registry"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	var deletes int
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			res, err := w.Next()
+			if err != nil {
+				return
+			}
+			if res.Action == "delete" {
+				deletes++
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 1; n <= 100; n++ {
+			_ = r.Register(node(n))
+		}
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		services, err := r.GetService("test.registry")
+		if err != nil || len(services) != 1 || len(services[0].Nodes) != 1 {
+			t.Fatalf("get service during update: %v, %v", services, err)
+		}
+	}
+
+	services, _ := r.GetService("test.registry")
+	if md := services[0].Nodes[0].Metadata; md["n"] != "100" {
+		t.Fatalf("metadata %v", md)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	w.Stop()
+	wg.Wait()
+	if deletes != 0 {
+		t.Fatalf("delete events %d, expected 0", deletes)
+	}
+}
+
+// grpc 传输保持默认启用TLS
+func TestPluginCmdOptions_Transport(t *testing.T) {
+	o := &cmd.Options{
+		Registries: make(map[string]func(...registry.Option) registry.Registry),
+		Brokers:    make(map[string]func(...broker.Option) broker.Broker),
+		Transports: make(map[string]func(...transport.Option) transport.Transport),
+	}
+	for _, opt := range pluginCmdOptions() {
+		opt(o)
+	}
+	if tr := o.Transports[DefaultTransport](); !tr.Options().Secure {
+		t.Fatal("grpc transport is not secure")
+	}
+}
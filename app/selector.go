@@ -2,37 +2,119 @@ package app
 
 import (
 	"fmt"
+	"github.com/cbwfree/micro-game/utils/dtype"
+	"github.com/cbwfree/micro-game/utils/hashring"
 	"github.com/micro/go-micro/v2/client"
 	"github.com/micro/go-micro/v2/client/selector"
 	"github.com/micro/go-micro/v2/registry"
+	"sort"
+	"strings"
+	"sync"
 )
 
+// 服务一致性哈希环缓存 (节点变化时重建)
+var rings = struct {
+	sync.Mutex
+	m map[string]*serviceRing
+}{m: make(map[string]*serviceRing)}
+
+type serviceRing struct {
+	sign string
+	ring *hashring.Ring
+}
+
+// 获取服务节点的一致性哈希环
+func getServiceRing(srvName string, nodeIds []string) *hashring.Ring {
+	sort.Strings(nodeIds)
+	sign := strings.Join(nodeIds, ",")
+
+	rings.Lock()
+	defer rings.Unlock()
+
+	if sr, ok := rings.m[srvName]; ok && sr.sign == sign {
+		return sr.ring
+	}
+
+	ring := hashring.New(hashring.DefaultReplicas, nodeIds...)
+	rings.m[srvName] = &serviceRing{sign: sign, ring: ring}
+	return ring
+}
+
+// 根据节点过滤服务 (仅保留有节点的服务)
+func filterNodes(old []*registry.Service, fn func(service *registry.Service, node *registry.Node) bool) []*registry.Service {
+	var services []*registry.Service
+
+	for _, service := range old {
+		serv := new(registry.Service)
+		var nodes []*registry.Node
+
+		for _, node := range service.Nodes {
+			if fn(service, node) {
+				nodes = append(nodes, node)
+			}
+		}
+
+		// only add service if there's some nodes
+		if len(nodes) > 0 {
+			// copy
+			*serv = *service
+			serv.Nodes = nodes
+			services = append(services, serv)
+		}
+	}
+
+	return services
+}
+
 // 根据节点ID过滤服务
 func FilterNodeId(srvName, id string) selector.Filter {
+	var nodeId = fmt.Sprintf("%s-%s", srvName, id)
 	return func(old []*registry.Service) []*registry.Service {
-		var services []*registry.Service
-		var nodeId = fmt.Sprintf("%s-%s", srvName, id)
+		return filterNodes(old, func(_ *registry.Service, node *registry.Node) bool {
+			return node.Id == nodeId
+		})
+	}
+}
 
-		for _, service := range old {
-			serv := new(registry.Service)
-			var nodes []*registry.Node
+// 根据meta信息过滤服务节点 (值相等)
+func FilterMetadata(key, value string) selector.Filter {
+	return FilterMetadataFunc(func(md map[string]string) bool {
+		return md[key] == value
+	})
+}
+
+// 根据meta信息过滤服务节点 (自定义条件)
+func FilterMetadataFunc(fn func(md map[string]string) bool) selector.Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		return filterNodes(old, func(_ *registry.Service, node *registry.Node) bool {
+			return fn(node.Metadata)
+		})
+	}
+}
+
+// 根据版本过滤服务
+func FilterVersion(version string) selector.Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		return filterNodes(old, func(service *registry.Service, _ *registry.Node) bool {
+			return service.Version == version
+		})
+	}
+}
 
+// 选择连接数最少的节点 (节点meta信息 connections, 未上报时视为0)
+func FilterLeastConn() selector.Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		var least = -1
+		for _, service := range old {
 			for _, node := range service.Nodes {
-				if node.Id == nodeId {
-					nodes = append(nodes, node)
+				if n := dtype.ParseInt(node.Metadata[MetaConnections]); least < 0 || n < least {
+					least = n
 				}
 			}
-
-			// only add service if there's some nodes
-			if len(nodes) > 0 {
-				// copy
-				*serv = *service
-				serv.Nodes = nodes
-				services = append(services, serv)
-			}
 		}
-
-		return services
+		return filterNodes(old, func(_ *registry.Service, node *registry.Node) bool {
+			return dtype.ParseInt(node.Metadata[MetaConnections]) == least
+		})
 	}
 }
 
@@ -45,3 +127,26 @@ func FilterSelector(filter selector.Filter) client.CallOption {
 func FilterServiceNode(srvName, nodeId string) client.CallOption {
 	return FilterSelector(FilterNodeId(srvName, nodeId))
 }
+
+// 根据一致性哈希过滤服务 (同一键固定选择同一节点, 节点变化时仅少量键重新映射)
+func FilterHash(srvName string, key interface{}) selector.Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		var nodeIds []string
+		for _, service := range old {
+			for _, node := range service.Nodes {
+				nodeIds = append(nodeIds, node.Id)
+			}
+		}
+		if len(nodeIds) == 0 {
+			return old
+		}
+
+		nodeId := getServiceRing(srvName, nodeIds).Get(dtype.ParseStr(key))
+		return FilterNodeId(srvName, strings.TrimPrefix(nodeId, srvName+"-"))(old)
+	}
+}
+
+// 按键一致性哈希选择服务节点 (如角色ID, 服务器ID)
+func FilterServiceHash(srvName string, key interface{}) client.CallOption {
+	return FilterSelector(FilterHash(srvName, key))
+}
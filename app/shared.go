@@ -0,0 +1,90 @@
+package app
+
+import (
+	"github.com/cbwfree/micro-game/utils/health"
+	"github.com/google/gops/agent"
+	"sync"
+	"sync/atomic"
+)
+
+// 进程内共享的服务 (链路跟踪、监控指标、健康检查、gops), 首个服务启动时开启, 最后一个服务停止时关闭
+var shared struct {
+	sync.Mutex
+	running int // 已启动的服务数
+	ready   int // 已就绪的服务数
+}
+
+// 更新进程就绪状态 (全部已启动的服务均就绪时为就绪)
+func updateReady() {
+	health.SetReady(shared.running > 0 && shared.ready == shared.running)
+}
+
+// 设置服务就绪状态 (服务启动完成后设置为true, 停止前设置为false)
+func (s *Service) setReady(ready bool) {
+	shared.Lock()
+	defer shared.Unlock()
+
+	if ready && atomic.CompareAndSwapInt32(&s.running, 0, 1) {
+		shared.ready++
+	} else if !ready && atomic.CompareAndSwapInt32(&s.running, 1, 0) {
+		shared.ready--
+	}
+	updateReady()
+}
+
+// 服务启动前处理
+func (s *Service) startShared() error {
+	// gRPC健康检查 (grpc.health.v1.Health/Check, 与服务共用端口)
+	if Opts.HealthAddr != "" {
+		s.AddHandler(new(Health))
+	}
+
+	shared.Lock()
+	defer shared.Unlock()
+
+	shared.running++
+	updateReady()
+	if shared.running > 1 {
+		return nil
+	}
+
+	for _, start := range []func() error{
+		func() error { return startTrace(s.Name()) },
+		func() error { return startMetrics(s.Name()) },
+		startHealth,
+		startGops,
+	} {
+		if err := start(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 服务停止后处理
+func (s *Service) stopShared() error {
+	s.RemoveHealthCheck(RegistryHealthCheck)
+
+	shared.Lock()
+	defer shared.Unlock()
+
+	shared.running--
+	updateReady()
+	if shared.running > 0 {
+		return nil
+	}
+
+	agent.Close()
+	_ = stopHealth()
+	_ = stopMetrics()
+	return stopTrace()
+}
+
+// 启用gops分析
+func startGops() error {
+	if Opts.PsAddr == "" {
+		return nil
+	}
+	return agent.Listen(agent.Options{Addr: Opts.PsAddr})
+}
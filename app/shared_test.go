@@ -0,0 +1,59 @@
+package app
+
+import (
+	"github.com/cbwfree/micro-game/utils/health"
+	"testing"
+)
+
+// 同一进程的多个服务, 全部已启动的服务就绪时进程才就绪
+func TestService_Ready(t *testing.T) {
+	a, b := NewService("test.ready.a", "latest"), NewService("test.ready.b", "latest")
+
+	var steps = []struct {
+		fn    func() error
+		ready bool
+	}{
+		{a.startShared, false},
+		{func() error { a.setReady(true); return nil }, true},
+		{b.startShared, false},
+		{func() error { b.setReady(true); return nil }, true},
+		{func() error { b.setReady(false); return nil }, false},
+		{func() error { b.setReady(false); return nil }, false},
+		{b.stopShared, true},
+		{func() error { a.setReady(false); return nil }, false},
+		{a.stopShared, false},
+	}
+	for i, step := range steps {
+		if err := step.fn(); err != nil {
+			t.Fatal(err)
+		}
+		if health.IsReady() != step.ready {
+			t.Fatalf("step %d: expected ready %v", i, step.ready)
+		}
+	}
+}
+
+// 同名服务的健康检查互不覆盖, 注销时只移除自身的检查
+func TestService_HealthCheck(t *testing.T) {
+	a, b := NewService("test.health", "latest"), NewService("test.health", "latest")
+	defer b.RemoveHealthCheck(RegistryHealthCheck)
+
+	var names = make(map[string]bool)
+	for _, name := range health.Names() {
+		names[name] = true
+	}
+	if !names[a.healthName(RegistryHealthCheck)] || !names[b.healthName(RegistryHealthCheck)] {
+		t.Fatalf("health checks %v", health.Names())
+	}
+
+	a.RemoveHealthCheck(RegistryHealthCheck)
+	for _, name := range health.Names() {
+		if name == a.healthName(RegistryHealthCheck) {
+			t.Fatal("health check not removed")
+		}
+		names[name] = false
+	}
+	if names[b.healthName(RegistryHealthCheck)] {
+		t.Fatal("health check of other service removed")
+	}
+}
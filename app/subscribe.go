@@ -0,0 +1,265 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/cbwfree/micro-game/utils/pb"
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"strconv"
+	"time"
+
+	rds "github.com/cbwfree/micro-game/store/redis"
+)
+
+const (
+	MetaMessageId        = "Message-Id"     // 消息ID (发布时生成, 用于幂等处理)
+	MetaSubAttempt       = "Sub-Attempt"    // 订阅处理次数 (延迟重试时设置)
+	MetaSubTarget        = "Sub-Target"     // 延迟重试目标 (服务名称或服务节点)
+	DeadLetterTopic      = "dead_letter"    // 默认死信主题
+	DefaultSubMaxBackoff = 30 * time.Second // 重试最大间隔
+
+	subProcessing = "processing" // 幂等状态: 处理中
+	subDone       = "done"       // 幂等状态: 已处理
+)
+
+// 死信事件
+var DeadLetterEvent = NewEvent(DeadLetterTopic, new(pb.DeadLetter))
+
+type SubOption func(o *SubOptions)
+
+// 订阅选项 (同一主题的订阅共用)
+type SubOptions struct {
+	Queue      bool          // 队列订阅 (同一服务仅一个节点处理)
+	Retry      int           // 失败重试次数
+	Backoff    time.Duration // 重试间隔 (指数增长)
+	DeadLetter string        // 死信主题 (重试后仍失败时发布), 为空不发布
+	Idempotent time.Duration // 幂等记录保存时间 (按消息ID去重), 为0不启用
+}
+
+// SubQueue 队列订阅
+func SubQueue() SubOption {
+	return func(o *SubOptions) {
+		o.Queue = true
+	}
+}
+
+// SubRetry 失败重试 (间隔为 backoff, 2*backoff, 4*backoff ..., 通过延迟消息重新投递, 需连接Redis)
+func SubRetry(n int, backoff time.Duration) SubOption {
+	return func(o *SubOptions) {
+		o.Retry = n
+		o.Backoff = backoff
+	}
+}
+
+// SubDeadLetter 处理失败时发布到死信主题 (为空时使用默认主题)
+func SubDeadLetter(topic ...string) SubOption {
+	return func(o *SubOptions) {
+		o.DeadLetter = DeadLetterTopic
+		if len(topic) > 0 && topic[0] != "" {
+			o.DeadLetter = topic[0]
+		}
+	}
+}
+
+// SubIdempotent 按消息ID幂等处理 (记录保存在Redis中, 处理中的消息重复投递时返回错误)
+func SubIdempotent(ttl time.Duration) SubOption {
+	return func(o *SubOptions) {
+		o.Idempotent = ttl
+	}
+}
+
+// MessageId 获取当前消息ID (订阅处理中使用)
+func MessageId(ctx context.Context) string {
+	id, _ := metadata.Get(ctx, MetaMessageId)
+	return id
+}
+
+// 设置消息ID (上下文中已有消息ID时保留)
+func withMessageId(ctx context.Context) context.Context {
+	if id := MessageId(ctx); id != "" {
+		return ctx
+	}
+	return newMessageContext(ctx, "")
+}
+
+// 设置新的消息ID
+func newMessageContext(ctx context.Context, id string) context.Context {
+	if id == "" {
+		id = uuid.New().String()
+	}
+	return metadata.Set(ctx, MetaMessageId, id)
+}
+
+// Subscribe 注册订阅
+func (s *Service) Subscribe(name string, h interface{}, opts ...SubOption) error {
+	o := new(SubOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if e, ok := GetEvent(name); ok && isFunc(h) {
+		if err := e.checkHandler(h); err != nil {
+			return err
+		}
+	}
+
+	var sOpts = []server.SubscriberOption{
+		server.InternalSubscriber(true),
+	}
+	if o.Queue {
+		sOpts = append(sOpts, server.SubscriberQueue(name))
+	}
+
+	if err := micro.RegisterSubscriber(name, s.Server(), h, sOpts...); err != nil {
+		return err
+	}
+
+	s.Lock()
+	s.subOpts[name] = o
+	s.Unlock()
+
+	return nil
+}
+
+// 获取订阅选项
+func (s *Service) subOptions(topic string) *SubOptions {
+	s.RLock()
+	defer s.RUnlock()
+
+	if o, ok := s.subOpts[topic]; ok {
+		return o
+	}
+	return new(SubOptions)
+}
+
+// 处理订阅消息 (幂等检查, 失败延迟重试, 死信)
+func (s *Service) consume(ctx context.Context, msg server.Message, fn server.SubscriberFunc) error {
+	o := s.subOptions(msg.Topic())
+	id := MessageId(ctx)
+
+	// 延迟重试的消息仅由重试目标处理
+	header := msg.Header()
+	if target := header[MetaSubTarget]; target != "" && target != s.Name() && target != s.NameId() {
+		return nil
+	}
+	attempts := 1
+	if n, err := strconv.Atoi(header[MetaSubAttempt]); err == nil && n > 1 {
+		attempts = n
+	}
+
+	// 幂等处理: 先标记处理中, 成功后标记已处理, 失败时释放
+	// 处理中的消息再次投递时返回错误, 由投递方稍后重新投递
+	var key string
+	if o.Idempotent > 0 && id != "" && rds.Client() != nil {
+		key = fmt.Sprintf("sub:%s:%s:%s", s.Name(), msg.Topic(), id)
+		ok, err := rds.Client().SetNX(ctx, key, subProcessing, o.Idempotent).Result()
+		if err != nil {
+			log.Warn("[Subscriber] idempotent check [%s] error: %s", key, err)
+			key = ""
+		} else if !ok {
+			state, err := rds.Client().Get(ctx, key).Result()
+			if err == nil && state == subDone {
+				log.Debug("[Subscriber] message [%s] topic [%s] already processed", id, msg.Topic())
+				s.ackDelay(msg)
+				return nil
+			}
+			return errors.Started("message %s topic %s is being processed", id, msg.Topic())
+		}
+	}
+
+	err := fn(ctx, msg)
+	if err == nil {
+		if key != "" {
+			if err := rds.Client().Set(context.Background(), key, subDone, o.Idempotent).Err(); err != nil {
+				log.Warn("[Subscriber] idempotent done [%s] error: %s", key, err)
+			}
+		}
+		s.ackDelay(msg)
+		return nil
+	}
+
+	if key != "" {
+		_ = rds.Client().Del(context.Background(), key).Err()
+	}
+
+	// 通过延迟消息重试, 不阻塞订阅处理
+	if attempts <= o.Retry {
+		rerr := s.retryLater(ctx, msg, id, attempts, o)
+		if rerr == nil {
+			s.ackDelay(msg)
+			return nil
+		}
+		log.Warn("[Subscriber] retry message [%s] topic [%s] error: %s", id, msg.Topic(), rerr)
+	}
+
+	// 发布死信后视为已处理, 否则延迟消息在租约到期后重新投递
+	if o.DeadLetter != "" && o.DeadLetter != msg.Topic() {
+		if s.deadLetter(o.DeadLetter, msg, id, attempts, err) {
+			s.ackDelay(msg)
+		}
+	}
+
+	return err
+}
+
+// 延迟重试订阅消息 (第n次重试间隔为 backoff*2^(n-1)), 仅由失败的服务 (队列订阅) 或服务节点处理
+func (s *Service) retryLater(ctx context.Context, msg server.Message, id string, attempts int, o *SubOptions) error {
+	if rds.Client() == nil {
+		return errors.Unavailable("redis is not connected")
+	}
+
+	backoff := o.Backoff << uint(attempts-1)
+	if backoff <= 0 || backoff > DefaultSubMaxBackoff {
+		backoff = DefaultSubMaxBackoff
+	}
+
+	target := s.NameId()
+	if o.Queue {
+		target = s.Name()
+	}
+
+	return s.delay().Add(ctx, &rds.DelayMessage{
+		Id:          uuid.New().String(),
+		Topic:       msg.Topic(),
+		ContentType: msg.ContentType(),
+		Body:        msg.Body(),
+		Header: map[string]string{
+			MetaMessageId:  id,
+			MetaSubAttempt: strconv.Itoa(attempts + 1),
+			MetaSubTarget:  target,
+		},
+		Due: time.Now().Add(backoff).UnixNano() / 1e6,
+	})
+}
+
+// 发布死信消息, 返回是否发布成功
+func (s *Service) deadLetter(topic string, msg server.Message, id string, attempts int, err error) bool {
+	dl := &pb.DeadLetter{
+		Topic:       msg.Topic(),
+		Service:     s.Name(),
+		NodeId:      s.Id(),
+		MessageId:   id,
+		ContentType: msg.ContentType(),
+		Body:        msg.Body(),
+		Error:       err.Error(),
+		Attempts:    int32(attempts),
+		Time:        time.Now().UnixNano() / 1e6,
+	}
+
+	ctx := newMessageContext(context.Background(), "")
+	if err := s.Client().Publish(ctx, s.Client().NewMessage(topic, dl)); err != nil {
+		log.Error("[Subscriber] publish dead letter [%s] topic [%s] error: %s", id, msg.Topic(), err)
+		return false
+	}
+	return true
+}
+
+// 注册订阅
+func Subscribe(name string, h interface{}, opts ...SubOption) error {
+	return Default().Subscribe(name, h, opts...)
+}
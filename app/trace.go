@@ -0,0 +1,36 @@
+package app
+
+import (
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/cbwfree/micro-game/utils/trace"
+)
+
+// 启用链路跟踪导出器
+func startTrace(name string) error {
+	switch Opts.TraceExporter {
+	case "":
+		return nil
+	case "otlp":
+		trace.SetExporter(trace.NewOTLPExporter(Opts.TraceAddress, name))
+	case "memory":
+		trace.SetExporter(trace.NewMemoryExporter())
+	default:
+		return errors.Invalid("unknown trace exporter: %s", Opts.TraceExporter)
+	}
+
+	log.Debug("[Trace] trace exporter [%s] is enabled", Opts.TraceExporter)
+
+	return nil
+}
+
+// 关闭链路跟踪导出器 (导出剩余跨度)
+func stopTrace() error {
+	if e := trace.GetExporter(); e != nil {
+		trace.SetExporter(nil)
+		if err := e.Close(); err != nil {
+			log.Warn("[Trace] close trace exporter error: %s", err)
+		}
+	}
+	return nil
+}
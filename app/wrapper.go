@@ -4,24 +4,31 @@ import (
 	"context"
 	"github.com/cbwfree/micro-game/utils/errors"
 	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/cbwfree/micro-game/utils/metrics"
+	"github.com/cbwfree/micro-game/utils/trace"
+	"github.com/micro/go-micro/v2/client"
 	"github.com/micro/go-micro/v2/server"
 	"time"
 )
 
-func serverWrapper(fn server.HandlerFunc) server.HandlerFunc {
+func (s *Service) serverWrapper(fn server.HandlerFunc) server.HandlerFunc {
 	return func(ctx context.Context, req server.Request, rsp interface{}) error {
 		now := time.Now()
+		ctx, span := trace.Start(ctx, req.Endpoint(), trace.KindServer)
+		span.SetAttr("service", req.Service())
 		err := fn(ctx, req, rsp)
+		span.Finish(err)
+		metrics.ObserveRPC(req.Endpoint(), time.Since(now), err)
 
 		if Opts.Dev {
 			if err != nil {
 				if errors.IsStack() {
-					log.Error("[Server] received [%s], uptime: %s, error: %+v", req.Endpoint(), time.Since(now), err)
+					log.Error("[Server] received [%s], trace: %s, uptime: %s, error: %+v", req.Endpoint(), span.TraceId, time.Since(now), err)
 				} else {
-					log.Error("[Server] received [%s], uptime: %s, error: %s", req.Endpoint(), time.Since(now), err)
+					log.Error("[Server] received [%s], trace: %s, uptime: %s, error: %s", req.Endpoint(), span.TraceId, time.Since(now), err)
 				}
 			} else {
-				log.Info("[Server] received [%s], uptime: %s", req.Endpoint(), time.Since(now))
+				log.Info("[Server] received [%s], trace: %s, uptime: %s", req.Endpoint(), span.TraceId, time.Since(now))
 			}
 		}
 
@@ -29,24 +36,27 @@ func serverWrapper(fn server.HandlerFunc) server.HandlerFunc {
 			return nil
 		}
 
-		return errors.MicroError(Id(), err)
+		return errors.MicroError(s.Id(), err)
 	}
 }
 
-func subscriberWrapper(fn server.SubscriberFunc) server.SubscriberFunc {
+func (s *Service) subscriberWrapper(fn server.SubscriberFunc) server.SubscriberFunc {
 	return func(ctx context.Context, msg server.Message) error {
 		now := time.Now()
-		err := fn(ctx, msg)
+		ctx, span := trace.Start(ctx, msg.Topic(), trace.KindConsumer)
+		err := s.consume(ctx, msg, fn)
+		span.Finish(err)
+		metrics.ObserveSubscriber(msg.Topic(), time.Since(now), err)
 
 		if Opts.Dev {
 			if err != nil {
 				if errors.IsStack() {
-					log.Error("[Subscriber] received [%s], uptime: %s, error: %+v", msg.Topic(), time.Since(now), err)
+					log.Error("[Subscriber] received [%s], trace: %s, uptime: %s, error: %+v", msg.Topic(), span.TraceId, time.Since(now), err)
 				} else {
-					log.Error("[Subscriber] received [%s], uptime: %s, error: %s", msg.Topic(), time.Since(now), err)
+					log.Error("[Subscriber] received [%s], trace: %s, uptime: %s, error: %s", msg.Topic(), span.TraceId, time.Since(now), err)
 				}
 			} else {
-				log.Info("[Subscriber] received [%s], uptime: %s", msg.Topic(), time.Since(now))
+				log.Info("[Subscriber] received [%s], trace: %s, uptime: %s", msg.Topic(), span.TraceId, time.Since(now))
 			}
 		}
 
@@ -54,6 +64,30 @@ func subscriberWrapper(fn server.SubscriberFunc) server.SubscriberFunc {
 			return nil
 		}
 
-		return errors.MicroError(Id(), err)
+		return errors.MicroError(s.Id(), err)
 	}
 }
+
+// 客户端跟踪 (RPC调用及消息发布时记录客户端跨度, 下游跨度归属于该跨度)
+type traceClient struct {
+	client.Client
+}
+
+func (c *traceClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	ctx, span := trace.Start(ctx, req.Endpoint(), trace.KindClient)
+	span.SetAttr("service", req.Service())
+	err := c.Client.Call(ctx, req, rsp, opts...)
+	span.Finish(err)
+	return err
+}
+
+func (c *traceClient) Publish(ctx context.Context, msg client.Message, opts ...client.PublishOption) error {
+	ctx, span := trace.Start(ctx, msg.Topic(), trace.KindProducer)
+	err := c.Client.Publish(ctx, msg, opts...)
+	span.Finish(err)
+	return err
+}
+
+func (s *Service) clientWrapper(c client.Client) client.Client {
+	return &traceClient{Client: c}
+}
@@ -0,0 +1,212 @@
+package apptest
+
+import (
+	"github.com/cbwfree/micro-game/agent"
+	"github.com/cbwfree/micro-game/codec"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/pb"
+	"github.com/golang/protobuf/proto"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultCallTimeout = 5 * time.Second // 默认请求等待时间
+)
+
+// 服务端消息
+type Message struct {
+	Head *codec.ServerHead
+	Data []byte
+}
+
+// Unmarshal 解码消息数据 (错误码不为0时返回错误)
+func (m *Message) Unmarshal(msg proto.Message) error {
+	if m.Head.Code > 0 {
+		return errors.New(int32(m.Head.Code))
+	}
+	return proto.Unmarshal(m.Data, msg)
+}
+
+// Client 网关测试客户端 (TCP, 新版消息头)
+type Client struct {
+	sync.Mutex
+	conn    net.Conn
+	enc     *codec.ClientEncoder
+	serial  uint16
+	pending map[uint16]chan *Message
+	pushes  chan *Message
+	closed  chan struct{}
+	err     error
+}
+
+// Post 发送请求, 不等待响应
+func (c *Client) Post(cmd uint32, req proto.Message) (uint16, error) {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	return c.send(cmd, data, nil)
+}
+
+// Send 发送请求并等待响应 (响应为空且无错误码时网关不返回消息, 此时应使用 Post)
+func (c *Client) Send(cmd uint32, data []byte) (*Message, error) {
+	ch := make(chan *Message, 1)
+	serial, err := c.send(cmd, data, ch)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case m := <-ch:
+		return m, nil
+	case <-c.closed:
+		return nil, errors.Unavailable("client closed: %v", c.err)
+	case <-time.After(DefaultCallTimeout):
+		c.Lock()
+		delete(c.pending, serial)
+		c.Unlock()
+		return nil, errors.Timeout("command %d timeout", cmd)
+	}
+}
+
+// Call 发送请求并解码响应
+func (c *Client) Call(cmd uint32, req, rsp proto.Message) error {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	m, err := c.Send(cmd, data)
+	if err != nil {
+		return err
+	}
+	return m.Unmarshal(rsp)
+}
+
+// Push 等待服务器推送消息
+func (c *Client) Push(timeout time.Duration) (*Message, error) {
+	select {
+	case m := <-c.pushes:
+		return m, nil
+	case <-c.closed:
+		return nil, errors.Unavailable("client closed: %v", c.err)
+	case <-time.After(timeout):
+		return nil, errors.Timeout("wait push timeout")
+	}
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) send(cmd uint32, data []byte, ch chan *Message) (uint16, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.serial++
+	if c.serial == 0 {
+		c.serial = 1
+	}
+	if ch != nil {
+		c.pending[c.serial] = ch
+	}
+
+	head := &codec.ClientHead{Serial: c.serial, Cmd: cmd, Version: codec.Version2}
+	if err := c.enc.Encode(head, data); err != nil {
+		delete(c.pending, c.serial)
+		return 0, err
+	}
+	return c.serial, nil
+}
+
+// 读取服务端消息, 按请求序号分发响应, 推送消息 (无请求序号) 放入推送队列
+func (c *Client) read(dec *codec.ServerDecoder) {
+	defer close(c.closed)
+	defer dec.Release()
+
+	for {
+		head, data, err := dec.Decode()
+		if err != nil {
+			c.err = err
+			return
+		}
+		m := &Message{Head: head, Data: append([]byte(nil), data...)}
+
+		if head.Flags&codec.FlagPush != 0 || head.Serial == 0 {
+			select {
+			case c.pushes <- m:
+			default:
+			}
+			continue
+		}
+
+		c.Lock()
+		ch, ok := c.pending[head.Serial]
+		delete(c.pending, head.Serial)
+		c.Unlock()
+
+		if ok {
+			ch <- m
+		}
+	}
+}
+
+// Dial 连接网关 (连接后发送握手消息协商新版消息头)
+func Dial(addr string, mix ...uint8) (*Client, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		conn:    conn,
+		enc:     codec.NewClientEncoder(conn, codec.NewClient(mix...)),
+		pending: make(map[uint16]chan *Message),
+		pushes:  make(chan *Message, 64),
+		closed:  make(chan struct{}),
+	}
+	dec := codec.NewServerDecoder(conn, codec.NewServer(mix...))
+	if err := c.handshake(dec); err != nil {
+		dec.Release()
+		_ = conn.Close()
+		return nil, err
+	}
+	go c.read(dec)
+
+	return c, nil
+}
+
+// 握手 (握手消息及响应使用旧版消息头, 完成后切换为新版消息头)
+func (c *Client) handshake(dec *codec.ServerDecoder) error {
+	data, err := proto.Marshal(&pb.Handshake{Version: uint32(codec.Version2)})
+	if err != nil {
+		return err
+	}
+	if err := c.enc.Encode(&codec.ClientHead{Cmd: agent.CmdHandshake}, data); err != nil {
+		return err
+	}
+
+	_ = c.conn.SetReadDeadline(time.Now().Add(DefaultCallTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	dec.SetVersion(codec.Version1)
+	head, data, err := dec.Decode()
+	if err != nil {
+		return err
+	}
+	if head.Cmd != agent.CmdHandshake {
+		return errors.Invalid("unexpected handshake response command %d", head.Cmd)
+	}
+	m := &Message{Head: head, Data: data}
+	rsp := new(pb.Handshake)
+	if err := m.Unmarshal(rsp); err != nil {
+		return err
+	}
+	if rsp.Version != uint32(codec.Version2) {
+		return errors.Invalid("unsupported header version %d", rsp.Version)
+	}
+	dec.SetVersion(codec.Version2)
+	return nil
+}
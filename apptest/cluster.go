@@ -0,0 +1,232 @@
+// 进程内测试集群
+//
+// 每个服务均为完整的 app.Service (单进程模式), 共用进程内的内存服务发现及消息代理,
+// Redis 使用内存实现 (miniredis), 无需任何外部服务.
+//
+// 	func TestMain(m *testing.M) {
+// 		c, err := apptest.New()
+// 		...
+// 		_, err = c.AddService("gate", func(s *app.Service) error {
+// 			_, err := c.StartAgent(s, onReceive)
+// 			return err
+// 		})
+// 		...
+// 		code := m.Run()
+// 		c.Stop()
+// 		os.Exit(code)
+// 	}
+package apptest
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cbwfree/micro-game/agent"
+	"github.com/cbwfree/micro-game/app"
+	"github.com/cbwfree/micro-game/codec"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/micro/go-micro/v2"
+	"github.com/micro/go-micro/v2/client/selector"
+	"os"
+	"sync"
+	"time"
+
+	rds "github.com/cbwfree/micro-game/store/redis"
+
+	_ "github.com/cbwfree/micro-game/agent/tcp"
+)
+
+var (
+	DefaultStartTimeout = 5 * time.Second // 默认服务启动等待时间
+)
+
+// 解析参数时替换进程参数 (多个集群共用)
+var argsMu sync.Mutex
+
+// 运行中的服务
+type service struct {
+	*app.Service
+	done chan error
+}
+
+// Cluster 进程内测试集群
+type Cluster struct {
+	sync.Mutex
+	redis    *miniredis.Miniredis
+	services []*service
+	agents   []*agent.Agent
+}
+
+// Redis 内存Redis (可用于检查或修改数据)
+func (c *Cluster) Redis() *miniredis.Miniredis {
+	return c.redis
+}
+
+// AddService 创建并启动服务, 等待注册到服务发现
+// 	setup 在服务启动前执行 (注册RPC服务、订阅及网关等)
+// 	首个服务同时作为默认服务 (app 包级函数、网关及协议路由使用)
+func (c *Cluster) AddService(name string, setup func(s *app.Service) error, opts ...micro.Option) (*app.Service, error) {
+	s := app.NewService(name, "test")
+
+	// 以单进程模式解析参数 (忽略测试参数), 进程参数为全局变量, 并行创建服务时加锁
+	argsMu.Lock()
+	args := os.Args
+	os.Args = []string{name, "--standalone"}
+	s.Init(opts...)
+	os.Args = args
+	argsMu.Unlock()
+
+	if app.Default() == nil {
+		app.SetDefault(s)
+	}
+
+	if setup != nil {
+		if err := setup(s); err != nil {
+			return nil, err
+		}
+	}
+
+	srv := &service{Service: s, done: make(chan error, 1)}
+	go func() {
+		srv.done <- s.Run()
+	}()
+
+	c.Lock()
+	c.services = append(c.services, srv)
+	c.Unlock()
+
+	err := waitFor(func() error {
+		select {
+		case err := <-srv.done:
+			srv.done <- err
+			return errors.Server("service %s exited: %v", name, err)
+		default:
+		}
+		return s.Registered(context.Background())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// RemoveService 停止并移除服务 (测试中临时增加的节点, 可在 t.Cleanup 中调用)
+func (c *Cluster) RemoveService(s *app.Service) {
+	c.Lock()
+	var srv *service
+	for i, v := range c.services {
+		if v.Service == s {
+			srv = v
+			c.services = append(c.services[:i], c.services[i+1:]...)
+			break
+		}
+	}
+	c.Unlock()
+
+	if srv == nil {
+		return
+	}
+	srv.Close()
+	select {
+	case <-srv.done:
+	case <-time.After(DefaultStartTimeout):
+	}
+	if app.Default() == s {
+		app.SetDefault(nil)
+	}
+
+	// 重建服务选择缓存 (缓存的监听启动前注销的节点不会从缓存中删除)
+	c.Lock()
+	defer c.Unlock()
+
+	var inited = make(map[selector.Selector]bool)
+	for _, v := range c.services {
+		if sel := v.Client().Options().Selector; !inited[sel] {
+			inited[sel] = true
+			_ = sel.Init()
+		}
+	}
+}
+
+// StartAgent 启动TCP网关 (监听本地随机端口), 并在服务中注册网关推送服务 (AddService setup 中使用)
+func (c *Cluster) StartAgent(s *app.Service, onReceive func(agent.Client, *codec.ClientHead, []byte) (*codec.ServerHead, []byte, error), opts ...agent.Option) (*agent.Agent, error) {
+	agent.Opts.Type = "tcp"
+	agent.Opts.Host = "127.0.0.1"
+
+	opts = append([]agent.Option{
+		agent.WithAddress("127.0.0.1:0"),
+		agent.WithWaitAuthTime(time.Minute),
+		agent.WithService(s),
+	}, opts...)
+
+	g := agent.NewAgent(nil, opts...)
+	g.SetOnReceive(onReceive)
+	g.SetOnDisconnect(func(client agent.Client) {
+		_ = agent.DeleteMetaCache(client.Meta())
+	})
+	if err := g.Run(); err != nil {
+		return nil, err
+	}
+	s.AddHandler(agent.NewGate(g))
+
+	c.Lock()
+	c.agents = append(c.agents, g)
+	c.Unlock()
+
+	return g, nil
+}
+
+// Stop 停止全部服务
+func (c *Cluster) Stop() {
+	c.Lock()
+	defer c.Unlock()
+
+	for _, g := range c.agents {
+		g.Close()
+	}
+	for i := len(c.services) - 1; i >= 0; i-- {
+		srv := c.services[i]
+		srv.Close()
+		select {
+		case <-srv.done:
+		case <-time.After(DefaultStartTimeout):
+		}
+		if app.Default() == srv.Service {
+			app.SetDefault(nil)
+		}
+	}
+
+	_ = rds.Disconnect()
+	c.redis.Close()
+}
+
+// New 创建测试集群 (启动内存Redis)
+func New() (*Cluster, error) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	rds.S().Init(rds.WithUrl(mr.Addr()))
+	if err := rds.Connect(); err != nil {
+		mr.Close()
+		return nil, err
+	}
+
+	return &Cluster{redis: mr}, nil
+}
+
+// 等待条件满足
+func waitFor(fn func() error) error {
+	deadline := time.Now().Add(DefaultStartTimeout)
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Timeout("wait for service: %s", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
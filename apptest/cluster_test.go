@@ -0,0 +1,537 @@
+package apptest
+
+import (
+	"context"
+	"fmt"
+	"github.com/cbwfree/micro-game/agent"
+	"github.com/cbwfree/micro-game/app"
+	"github.com/cbwfree/micro-game/codec"
+	"github.com/cbwfree/micro-game/protocol"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/pb"
+	"github.com/cbwfree/micro-game/utils/trace"
+	"github.com/golang/protobuf/proto"
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/metadata"
+	"net/http"
+	"os"
+	"sync"
+	"strings"
+	"testing"
+	"time"
+
+	rds "github.com/cbwfree/micro-game/store/redis"
+)
+
+const (
+	testGate     = "test.gate"
+	testGame     = "test.game"
+	testForward  = "Forward.Protocol"
+	testPushCmd  = 20001
+	testLoginCmd = 10001
+	testRoleId   = 2001
+)
+
+// 延迟消息事件
+var testDelayEvent = app.NewEvent("test.delay", new(pb.Cancel))
+
+// 重试消息事件
+var testRetryEvent = app.NewEvent("test.retry", new(pb.Cancel))
+
+// 重试消息处理 (retry 首次失败, dead 总是失败)
+var retryCalls = struct {
+	sync.Mutex
+	m map[string]int
+}{m: make(map[string]int)}
+
+func testRetryHandler(_ context.Context, msg *pb.Cancel) error {
+	retryCalls.Lock()
+	retryCalls.m[msg.Name]++
+	n := retryCalls.m[msg.Name]
+	retryCalls.Unlock()
+
+	if msg.Name == "dead" || (msg.Name == "retry" && n == 1) {
+		return errors.Server("handle %s failed", msg.Name)
+	}
+	return nil
+}
+
+// 重置重试消息处理次数 (-count 多次执行时各次测试互不影响)
+func resetRetryCalls() {
+	retryCalls.Lock()
+	defer retryCalls.Unlock()
+	retryCalls.m = make(map[string]int)
+}
+
+func retryCount(name string) int {
+	retryCalls.Lock()
+	defer retryCalls.Unlock()
+	return retryCalls.m[name]
+}
+
+var (
+	cluster     *Cluster
+	delayed     = make(chan *pb.Cancel, 1)
+	deadLetters = make(chan *pb.DeadLetter, 1)
+	gate        *agent.Agent
+	router      = protocol.NewRouter()
+)
+
+type testLogin struct{}
+
+func (*testLogin) Login_10001(ctx context.Context, c2s *pb.Cancel, s2c *pb.Cancel) error {
+	gmt, _ := protocol.FromContext(ctx)
+	gmt.Set(agent.MetaAccountId, 1001)
+	gmt.Set(agent.MetaRoleId, testRoleId)
+	if err := router.UpdateAuth(ctx, gmt); err != nil {
+		return err
+	}
+	s2c.Name = c2s.Name
+	s2c.NodeId = gmt.ClientId()
+	return nil
+}
+
+func (*testLogin) Notice_10002(ctx context.Context, c2s *pb.Cancel, s2c *pb.Cancel) error {
+	gmt, _ := protocol.FromContext(ctx)
+	if err := router.PushClient(ctx, gmt.ClientId(), &pb.Push{Cmd: testPushCmd, Data: []byte(c2s.Name)}); err != nil {
+		return err
+	}
+	s2c.Name = c2s.Name
+	return nil
+}
+
+// 游戏服转发服务
+type Forward struct{}
+
+func (*Forward) Protocol(ctx context.Context, req *pb.Push, rsp *pb.Push) error {
+	gmt, err := agent.FromMeta(ctx)
+	if err != nil {
+		return err
+	}
+	if req.Cmd != testLoginCmd && gmt.AccountId() == 0 {
+		rsp.Code = http.StatusUnauthorized
+		return nil
+	}
+	if data, err := router.CallCtx(ctx, gmt, req.Cmd, req.Data); err != nil {
+		rsp.Code = uint32(errors.Parse(err).Code)
+	} else {
+		rsp.Data = data
+	}
+	return nil
+}
+
+// 网关转发消息到游戏服 (游戏服登录后通过 router.UpdateAuth 同步认证信息)
+func testOnReceive(client agent.Client, head *codec.ClientHead, data []byte) (*codec.ServerHead, []byte, error) {
+	out := new(pb.Push)
+	ctx := client.Meta().RequestContext(head.Serial)
+	if err := app.CallCtx(ctx, testGame, testForward, &pb.Push{Cmd: head.Cmd, Data: data}, out); err != nil {
+		return nil, nil, err
+	}
+	return &codec.ServerHead{Serial: head.Serial, Cmd: head.Cmd, Code: out.Code}, out.Data, nil
+}
+
+func TestMain(m *testing.M) {
+	if err := router.AddRoute(new(testLogin)); err != nil {
+		panic(fmt.Sprintf("add route error: %s", err))
+	}
+	if err := router.AddPush(testPushCmd, &pb.Push{}); err != nil {
+		panic(fmt.Sprintf("add push error: %s", err))
+	}
+
+	var err error
+	if cluster, err = New(); err != nil {
+		panic(fmt.Sprintf("new cluster error: %s", err))
+	}
+	if _, err := cluster.AddService(testGate, func(s *app.Service) error {
+		gate, err = cluster.StartAgent(s, testOnReceive)
+		return err
+	}); err != nil {
+		panic(fmt.Sprintf("add gate service error: %s", err))
+	}
+	if _, err := cluster.AddService(testGame, func(s *app.Service) error {
+		router.Options().Init(protocol.WithService(s))
+		s.AddHandler(new(Forward))
+		if err := s.SubEvent(testRetryEvent, testRetryHandler,
+			app.SubQueue(),
+			app.SubRetry(2, 10*time.Millisecond),
+			app.SubDeadLetter(),
+			app.SubIdempotent(time.Minute),
+		); err != nil {
+			return err
+		}
+		if err := s.SubEvent(app.DeadLetterEvent, func(_ context.Context, msg *pb.DeadLetter) error {
+			deadLetters <- msg
+			return nil
+		}); err != nil {
+			return err
+		}
+		return s.SubEvent(testDelayEvent, func(_ context.Context, msg *pb.Cancel) error {
+			delayed <- msg
+			return nil
+		}, app.SubQueue())
+	}); err != nil {
+		panic(fmt.Sprintf("add game service error: %s", err))
+	}
+
+	code := m.Run()
+	cluster.Stop()
+	os.Exit(code)
+}
+
+func TestCluster_Login(t *testing.T) {
+	c, err := Dial(gate.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// 未登录
+	rsp := new(pb.Cancel)
+	if err := c.Call(10002, &pb.Cancel{Name: "hello"}, rsp); errors.Parse(err).Code != http.StatusUnauthorized {
+		t.Fatalf("call before login: %v", err)
+	}
+
+	// 登录
+	if err := c.Call(testLoginCmd, &pb.Cancel{Name: "tester"}, rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Name != "tester" || rsp.NodeId == "" {
+		t.Fatalf("login response: %+v", rsp)
+	}
+	if !cluster.Redis().Exists(agent.ClientCacheKey(rsp.NodeId)) {
+		t.Fatal("meta cache not saved")
+	}
+
+	// 登录后请求, 并接收推送
+	if err := c.Call(10002, &pb.Cancel{Name: "hello"}, rsp); err != nil {
+		t.Fatal(err)
+	}
+	m, err := c.Push(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	push := new(pb.Push)
+	if err := m.Unmarshal(push); err != nil {
+		t.Fatal(err)
+	}
+	if m.Head.Cmd != testPushCmd || string(push.Data) != "hello" {
+		t.Fatalf("push: cmd %d, %+v", m.Head.Cmd, push)
+	}
+}
+
+func TestCluster_Push(t *testing.T) {
+	ctx := context.Background()
+
+	var exists = gate.All()
+	c, err := Dial(gate.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Dial 等待握手完成, 此时网关已保存客户端Meta缓存
+	var clientId string
+	for id := range gate.All() {
+		if _, ok := exists[id]; !ok {
+			clientId = id
+		}
+	}
+
+	// 按客户端推送 (未登录)
+	if err := router.PushClient(ctx, clientId, &pb.Push{Cmd: testPushCmd, Data: []byte("client")}); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := c.Push(time.Second); err != nil || m.Head.Cmd != testPushCmd {
+		t.Fatalf("push client: %+v, %v", m, err)
+	}
+
+	// 登录后按角色推送
+	if err := c.Call(testLoginCmd, &pb.Cancel{Name: "tester"}, new(pb.Cancel)); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.PushRole(ctx, testRoleId, &pb.Push{Cmd: testPushCmd, Data: []byte("role")}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := c.Push(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	push := new(pb.Push)
+	if err := m.Unmarshal(push); err != nil || string(push.Data) != "role" {
+		t.Fatalf("push role: %+v, %v", push, err)
+	}
+
+	// 断开连接后删除Meta缓存
+	_ = c.Close()
+	if err := waitFor(func() error {
+		if cluster.Redis().Exists(agent.ClientCacheKey(clientId)) {
+			return errors.Unavailable("meta cache exists")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCluster_PubDelay(t *testing.T) {
+	ctx := context.Background()
+
+	id, err := app.PubDelay(ctx, testDelayEvent.Topic(), &pb.Cancel{Name: "cancel"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := app.CancelDelay(ctx, id); err != nil || !ok {
+		t.Fatalf("cancel delay: %v, %v", ok, err)
+	}
+
+	if _, err := app.PubDelay(ctx, testDelayEvent.Topic(), &pb.Cancel{Name: "delay"}, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-delayed:
+		if msg.Name != "delay" {
+			t.Fatalf("delayed message: %+v", msg)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("delayed message timeout")
+	}
+
+	// 投递节点无需注册消息类型, 订阅者处理完成后确认
+	sched := rds.NewScheduler(rds.S(), app.DelaySchedulerName, nil)
+	body, _ := proto.Marshal(&pb.Cancel{Name: "raw"})
+	dm := &rds.DelayMessage{
+		Id:          fmt.Sprintf("test-raw-%d", time.Now().UnixNano()),
+		Topic:       testDelayEvent.Topic(),
+		Type:        "test.Unknown",
+		ContentType: app.DelayContentType,
+		Body:        body,
+		Due:         time.Now().UnixNano() / 1e6,
+	}
+	if err := sched.Add(ctx, dm); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-delayed:
+		if msg.Name != "raw" {
+			t.Fatalf("delayed message: %+v", msg)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("raw delayed message timeout")
+	}
+	err = waitFor(func() error {
+		_, err := sched.Get(ctx, dm.Id)
+		if err == nil || !errors.IsCode(err, errors.CodeNotFound) {
+			return errors.Server("delay message not acked: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCluster_Cron(t *testing.T) {
+	var runs = make(chan string, 16)
+	var name = fmt.Sprintf("tick-%d", time.Now().UnixNano()) // 任务执行记录保存在Redis中, 每次测试使用不同的任务名称
+
+	// 同一服务的两个节点, 每秒执行的任务仅由一个节点执行
+	var srv *app.Service
+	for i := 0; i < 2; i++ {
+		s, err := cluster.AddService("test.cron", func(s *app.Service) error {
+			return s.AddCron(name, "* * * * * *", func(ctx context.Context) error {
+				runs <- s.Id()
+				return nil
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { cluster.RemoveService(s) })
+		srv = s
+	}
+
+	var history []*app.CronRun
+	err := waitFor(func() (err error) {
+		if history, err = srv.CronRuns(context.Background(), name, 0); err != nil {
+			return err
+		}
+		if len(history) < 2 || len(history) != len(runs) {
+			return errors.Server("cron runs %d, history %d", len(runs), len(history))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticks := make(map[time.Time]bool)
+	for _, run := range history {
+		if ticks[run.Tick] {
+			t.Fatalf("tick %s run twice", run.Tick)
+		}
+		ticks[run.Tick] = true
+	}
+}
+
+func TestCluster_SubRetry(t *testing.T) {
+	ctx := context.Background()
+	resetRetryCalls()
+
+	if err := app.PubEvent(ctx, testRetryEvent, &pb.Cancel{Name: "retry"}); err != nil {
+		t.Fatal(err)
+	}
+	// 重试不在订阅处理中等待
+	if n := retryCount("retry"); n != 1 {
+		t.Fatalf("retry calls %d before delay, expected 1", n)
+	}
+	// 处理失败时通过延迟消息重试, 发布者不会收到处理错误
+	if err := app.PubEvent(ctx, testRetryEvent, &pb.Cancel{Name: "dead"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case dl := <-deadLetters:
+		if dl.Topic != testRetryEvent.Topic() || dl.Attempts != 3 || dl.MessageId == "" || dl.Error == "" {
+			t.Fatalf("dead letter: %+v", dl)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("dead letter timeout")
+	}
+	if n := retryCount("retry"); n != 2 {
+		t.Fatalf("retry calls %d, expected 2", n)
+	}
+
+	// 相同消息ID重复投递, 仅处理一次
+	mctx := metadata.Set(ctx, app.MetaMessageId, fmt.Sprintf("test-once-%d", time.Now().UnixNano()))
+	for i := 0; i < 2; i++ {
+		if err := app.Client().Publish(mctx, app.Client().NewMessage(testRetryEvent.Topic(), &pb.Cancel{Name: "once"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := retryCount("once"); n != 1 {
+		t.Fatalf("idempotent calls %d, expected 1", n)
+	}
+
+	// 处理中的消息重复投递时不处理也不丢弃, 释放后可再次处理
+	busyId := fmt.Sprintf("test-busy-%d", time.Now().UnixNano())
+	busyKey := fmt.Sprintf("sub:%s:%s:%s", testGame, testRetryEvent.Topic(), busyId)
+	if err := cluster.Redis().Set(busyKey, "processing"); err != nil {
+		t.Fatal(err)
+	}
+	bctx := metadata.Set(ctx, app.MetaMessageId, busyId)
+	_ = app.PubCtx(bctx, testRetryEvent.Topic(), &pb.Cancel{Name: "busy"})
+	time.Sleep(100 * time.Millisecond)
+	if n := retryCount("busy"); n != 0 {
+		t.Fatalf("processing calls %d, expected 0", n)
+	}
+	cluster.Redis().Del(busyKey)
+	for i := 0; i < 2; i++ {
+		// 上下文中已有消息ID时发布保留原消息ID
+		if err := app.PubCtx(bctx, testRetryEvent.Topic(), &pb.Cancel{Name: "busy"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := retryCount("busy"); n != 1 {
+		t.Fatalf("released calls %d, expected 1", n)
+	}
+	if v, _ := cluster.Redis().Get(busyKey); v != "done" {
+		t.Fatalf("idempotent state %q, expected done", v)
+	}
+}
+
+func TestCluster_FilterServiceHash(t *testing.T) {
+	// 增加游戏服节点 (测试结束后移除)
+	s, err := cluster.AddService(testGame, func(s *app.Service) error {
+		s.AddHandler(new(Forward))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cluster.RemoveService(s) })
+
+	var used = make(map[string]bool)
+	for roleId := int64(1); roleId <= 20; roleId++ {
+		node, err := app.GetHashServiceNode(testGame, roleId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		used[node.Id] = true
+
+		for i := 0; i < 3; i++ {
+			next, err := app.Client().Options().Selector.Select(testGame, selector.WithFilter(app.FilterHash(testGame, roleId)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n, err := next(); err != nil || n.Id != node.Id {
+				t.Fatalf("role %d selected %v, expected %s", roleId, n, node.Id)
+			}
+		}
+	}
+	if len(used) != 2 {
+		t.Fatalf("used nodes %v", used)
+	}
+
+	out := new(pb.Push)
+	if err := app.CallCtx(context.Background(), testGame, testForward, &pb.Push{Cmd: 10002}, out, app.FilterServiceHash(testGame, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if out.Code != http.StatusUnauthorized {
+		t.Fatalf("forward code %d", out.Code)
+	}
+}
+
+func TestCluster_SelectServiceNode(t *testing.T) {
+	err := app.SetMetadata(map[string]string{"type": "tcp", "agent": gate.Address()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := app.SelectServiceNode(testGate, app.FilterMetadata("type", "tcp"), app.FilterLeastConn())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Metadata["agent"] != gate.Address() || node.Metadata[app.MetaConnections] == "" {
+		t.Fatalf("gate node metadata: %v", node.Metadata)
+	}
+	// 单进程模式服务端监听本地回环地址
+	if !strings.HasPrefix(node.Address, "127.0.0.1:") {
+		t.Fatalf("gate node address: %s", node.Address)
+	}
+
+	if _, err := app.SelectServiceNode(testGate, app.FilterVersion("test")); err != nil {
+		t.Fatal(err)
+	}
+	for _, filter := range []selector.Filter{
+		app.FilterMetadata("type", "websocket"),
+		app.FilterMetadataFunc(func(md map[string]string) bool { return md["agent"] == "" }),
+		app.FilterVersion("latest"),
+	} {
+		if _, err := app.SelectServiceNode(testGate, filter); errors.Parse(err).Code != errors.CodeNotFound {
+			t.Fatalf("select filtered node: %v", err)
+		}
+	}
+}
+
+func TestCluster_Trace(t *testing.T) {
+	mem := trace.NewMemoryExporter()
+	trace.SetExporter(mem)
+	defer trace.SetExporter(nil)
+
+	ctx, root := trace.Start(context.Background(), "test", trace.KindInternal)
+	if err := app.CallCtx(ctx, testGame, testForward, &pb.Push{Cmd: 10002}, new(pb.Push)); err != nil {
+		t.Fatal(err)
+	}
+	root.Finish(nil)
+
+	// RPC调用记录客户端跨度, 服务端跨度归属于客户端跨度
+	var kinds = make(map[trace.Kind]*trace.Span)
+	for _, span := range mem.Trace(root.TraceId) {
+		kinds[span.Kind] = span
+	}
+	cs, ss := kinds[trace.KindClient], kinds[trace.KindServer]
+	if cs == nil || ss == nil || cs.ParentId != root.SpanId || ss.ParentId != cs.SpanId {
+		t.Fatalf("unexpected spans: %+v", kinds)
+	}
+}
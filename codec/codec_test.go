@@ -1,14 +1,16 @@
 package codec
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"testing"
 )
 
 func TestClient(t *testing.T) {
 	c := NewClient(1, 2, 3, 4)
 
-	res, err := c.Marshal(&ClientHead{0, 10001, 0}, []byte("server"))
+	res, err := c.Marshal(&ClientHead{Serial: 0, Cmd: 10001, DataLen: 0}, []byte("server"))
 	if err != nil {
 		fmt.Printf("Server Marshal Error: %s\n", err.Error())
 		return
@@ -26,7 +28,7 @@ func TestClient(t *testing.T) {
 func TestServer(t *testing.T) {
 	s := NewServer(3, 6, 7, 9)
 
-	res, err := s.Marshal(&ServerHead{0, 10001, 0, 0}, []byte("client"))
+	res, err := s.Marshal(&ServerHead{Serial: 0, Cmd: 10001, Code: 0, DataLen: 0}, []byte("client"))
 	if err != nil {
 		fmt.Printf("Client Marshal Error: %s", err.Error())
 		return
@@ -40,3 +42,133 @@ func TestServer(t *testing.T) {
 
 	fmt.Printf("Serial: %d, Cmd: %d, Code: %d, Data: %s\n", head.Serial, head.Cmd, head.Code, data)
 }
+
+func TestClientVersion2(t *testing.T) {
+	c := NewClient(1, 2)
+
+	res, err := c.Marshal(&ClientHead{Serial: 7, Cmd: 10001, Version: Version2, Flags: FlagChecksum}, []byte("server"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := c.HeadLenOfVersion(res[:c.HeadLen()], Version2); err != nil || n != 2+clientHeadLenV2+checksumLen {
+		t.Fatalf("unexpected head length: %d, %v", n, err)
+	}
+
+	head, data, err := c.UnmarshalVersion(res, Version2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Serial != 7 || head.Cmd != 10001 || head.Flags != FlagChecksum || string(data) != "server" {
+		t.Fatalf("unexpected head: %+v, data: %s", head, data)
+	}
+
+	// 数据被篡改时校验失败
+	res[len(res)-1] ^= 0xFF
+	if _, _, err := c.UnmarshalVersion(res, Version2); err == nil {
+		t.Fatal("expected checksum error")
+	}
+
+	// 旧版消息头仍可解码
+	old, _ := c.Marshal(&ClientHead{Serial: 1, Cmd: 10002}, []byte("old"))
+	if head, data, err := c.Unmarshal(old); err != nil || head.Version != Version1 || head.Cmd != 10002 || string(data) != "old" {
+		t.Fatalf("unexpected v1 head: %+v, data: %s, err: %v", head, data, err)
+	}
+}
+
+// 旧版消息 Serial 首字节与新版标识相同时仍按旧版解码
+func TestLegacySerialMarker(t *testing.T) {
+	c := NewClient(1, 2)
+	s := NewServer(1, 2)
+	for _, serial := range []uint16{0xF200, 0xF2F2, 0xF2FF} {
+		raw, err := c.Marshal(&ClientHead{Serial: serial, Cmd: 10001}, []byte("old"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, data, err := c.Unmarshal(raw)
+		if err != nil || head.Version != Version1 || head.Serial != serial || head.Cmd != 10001 || string(data) != "old" {
+			t.Fatalf("client serial %#x: %+v, data: %s, err: %v", serial, head, data, err)
+		}
+		if head, data, err = NewDecoder(bytes.NewReader(raw), c).Decode(); err != nil || head.Serial != serial || string(data) != "old" {
+			t.Fatalf("client decoder serial %#x: %+v, data: %s, err: %v", serial, head, data, err)
+		}
+
+		raw, err = s.Marshal(&ServerHead{Serial: serial, Cmd: 10001, Code: 500}, []byte("old"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sHead, data, err := s.Unmarshal(raw)
+		if err != nil || sHead.Version != Version1 || sHead.Serial != serial || sHead.Code != 500 || string(data) != "old" {
+			t.Fatalf("server serial %#x: %+v, data: %s, err: %v", serial, sHead, data, err)
+		}
+		if sHead, data, err = NewServerDecoder(bytes.NewReader(raw), s).Decode(); err != nil || sHead.Serial != serial || string(data) != "old" {
+			t.Fatalf("server decoder serial %#x: %+v, data: %s, err: %v", serial, sHead, data, err)
+		}
+	}
+}
+
+func TestServerVersion2(t *testing.T) {
+	s := NewServer()
+
+	res, err := s.Marshal(&ServerHead{Serial: 3, Cmd: 10001, Code: 500, Version: Version2, Flags: FlagPush}, []byte("client"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != serverHeadLenV2+len("client") {
+		t.Fatalf("unexpected length: %d", len(res))
+	}
+
+	head, data, err := s.UnmarshalVersion(res, Version2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Version != Version2 || head.Code != 500 || head.Flags != FlagPush || string(data) != "client" {
+		t.Fatalf("unexpected head: %+v, data: %s", head, data)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	c := NewClient(1, 2, 3, 4)
+	s := NewServer(1, 2, 3, 4)
+
+	var buf bytes.Buffer
+	enc := NewClientEncoder(&buf, c)
+	if err := enc.Encode(&ClientHead{Serial: 1, Cmd: 10001, Version: Version2, Flags: FlagChecksum}, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(&ClientHead{Serial: 2, Cmd: 10002, Version: Version2}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(&buf, c)
+	dec.SetVersion(Version2)
+	defer dec.Release()
+
+	head, data, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Cmd != 10001 || string(data) != "first" || dec.Version() != Version2 {
+		t.Fatalf("unexpected head: %+v, data: %s", head, data)
+	}
+	head, data, err = dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Cmd != 10002 || len(data) != 0 {
+		t.Fatalf("unexpected head: %+v, data: %s", head, data)
+	}
+	if _, _, err := dec.Decode(); err != io.EOF {
+		t.Fatalf("expected EOF, got: %v", err)
+	}
+
+	// 超过最大长度的消息
+	buf.Reset()
+	if err := NewEncoder(&buf, s).Encode(&ServerHead{Cmd: 10003}, make([]byte, 64)); err != nil {
+		t.Fatal(err)
+	}
+	sdec := NewServerDecoder(&buf, s)
+	sdec.SetMaxDataLen(32)
+	if _, _, err := sdec.Decode(); err == nil {
+		t.Fatal("expected data too large error")
+	}
+}
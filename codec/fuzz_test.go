@@ -0,0 +1,80 @@
+//go:build go1.18
+// +build go1.18
+
+package codec
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzClientUnmarshal(f *testing.F) {
+	c := NewClient(1, 2)
+	for _, version := range []uint8{Version1, Version2} {
+		raw, _ := c.Marshal(&ClientHead{Serial: 1, Cmd: 10001, Version: version, Flags: FlagChecksum}, []byte("data"))
+		f.Add(raw)
+	}
+	f.Add([]byte{1, 2, VersionMarker})
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		for _, version := range []uint8{Version1, Version2} {
+			head, data, err := c.UnmarshalVersion(raw, version)
+			if err == nil && data != nil && int(head.DataLen) != len(data) {
+				t.Fatalf("data length mismatch: %d != %d", head.DataLen, len(data))
+			}
+
+			// 流式解码不能panic
+			dec := NewDecoder(bytes.NewReader(raw), c)
+			dec.SetVersion(version)
+			_, _, _ = dec.Decode()
+			dec.Release()
+		}
+	})
+}
+
+func FuzzServerUnmarshal(f *testing.F) {
+	s := NewServer()
+	for _, version := range []uint8{Version1, Version2} {
+		raw, _ := s.Marshal(&ServerHead{Serial: 1, Cmd: 10001, Code: 500, Version: version, Flags: FlagPush}, []byte("data"))
+		f.Add(raw)
+	}
+
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		for _, version := range []uint8{Version1, Version2} {
+			head, data, err := s.UnmarshalVersion(raw, version)
+			if err == nil && data != nil && int(head.DataLen) != len(data) {
+				t.Fatalf("data length mismatch: %d != %d", head.DataLen, len(data))
+			}
+
+			dec := NewServerDecoder(bytes.NewReader(raw), s)
+			dec.SetVersion(version)
+			_, _, _ = dec.Decode()
+			dec.Release()
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add(uint16(1), uint32(10001), uint8(Version2), uint8(FlagChecksum), []byte("data"))
+
+	f.Fuzz(func(t *testing.T, serial uint16, cmd uint32, version uint8, flags uint8, data []byte) {
+		c := NewClient(3, 4)
+		raw, err := c.Marshal(&ClientHead{Serial: serial, Cmd: cmd, Version: version, Flags: flags}, data)
+		if err != nil {
+			if version <= Version2 {
+				t.Fatal(err)
+			}
+			return
+		}
+		if version == 0 {
+			version = Version1
+		}
+		head, out, err := c.UnmarshalVersion(raw, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head.Serial != serial || head.Cmd != cmd || !bytes.Equal(out, data) {
+			t.Fatalf("round trip mismatch: %+v", head)
+		}
+	})
+}
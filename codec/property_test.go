@@ -0,0 +1,114 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+	"testing/quick"
+)
+
+var byteOrders = []binary.ByteOrder{binary.BigEndian, binary.LittleEndian}
+
+// 随机混淆头 (0-4位)
+func randMix(r *rand.Rand) []uint8 {
+	mix := make([]uint8, r.Intn(5))
+	r.Read(mix)
+	return mix
+}
+
+// 随机消息头版本及标记位
+func randVersion(r *rand.Rand) (uint8, uint8) {
+	if r.Intn(2) == 0 {
+		return Version1, 0
+	}
+	return Version2, uint8(r.Intn(16))
+}
+
+func TestClientRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, bin := range byteOrders {
+		f := func(serial uint16, cmd uint32, data []byte) bool {
+			c := NewBinClient(bin, randMix(r)...)
+			version, flags := randVersion(r)
+
+			raw, err := c.Marshal(&ClientHead{Serial: serial, Cmd: cmd, Version: version, Flags: flags}, data)
+			if err != nil {
+				return false
+			}
+			head, out, err := c.UnmarshalVersion(raw, version)
+			if err != nil {
+				return false
+			}
+
+			// 流式解码结果一致
+			dec := NewDecoder(bytes.NewReader(raw), c)
+			dec.SetVersion(version)
+			sHead, sOut, err := dec.Decode()
+			if err != nil {
+				return false
+			}
+
+			return head.Serial == serial && head.Cmd == cmd && head.Flags == flags &&
+				int(head.DataLen) == len(data) && bytes.Equal(out, data) &&
+				*sHead == *head && bytes.Equal(sOut, data)
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Errorf("%s: %v", bin, err)
+		}
+	}
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, bin := range byteOrders {
+		f := func(serial uint16, cmd uint32, code uint32, data []byte) bool {
+			s := NewBinServer(bin, randMix(r)...)
+			version, flags := randVersion(r)
+
+			var buf bytes.Buffer
+			if err := NewEncoder(&buf, s).Encode(&ServerHead{Serial: serial, Cmd: cmd, Code: code, Version: version, Flags: flags}, data); err != nil {
+				return false
+			}
+			head, out, err := s.UnmarshalVersion(buf.Bytes(), version)
+			if err != nil {
+				return false
+			}
+
+			return head.Serial == serial && head.Cmd == cmd && head.Code == code && head.Flags == flags &&
+				int(head.DataLen) == len(data) && bytes.Equal(out, data)
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Errorf("%s: %v", bin, err)
+		}
+	}
+}
+
+// 截断的消息返回错误而不是panic
+func TestTruncatedFrames(t *testing.T) {
+	for _, mix := range [][]uint8{nil, {1}, {1, 2, 3, 4}} {
+		c := NewClient(mix...)
+		s := NewServer(mix...)
+		for _, version := range []uint8{Version1, Version2} {
+			raw, _ := c.Marshal(&ClientHead{Cmd: 1, Version: version, Flags: FlagChecksum}, []byte("payload"))
+			for i := 0; i < len(raw); i++ {
+				dec := NewDecoder(bytes.NewReader(raw[:i]), c)
+				dec.SetVersion(version)
+				if _, _, err := dec.Decode(); err == nil {
+					t.Fatalf("mix %v version %d: expected error for %d bytes", mix, version, i)
+				}
+				_, _, _ = c.UnmarshalVersion(raw[:i], version)
+			}
+
+			raw, _ = s.Marshal(&ServerHead{Cmd: 1, Version: version, Flags: FlagChecksum}, []byte("payload"))
+			for i := 0; i < len(raw); i++ {
+				dec := NewServerDecoder(bytes.NewReader(raw[:i]), s)
+				dec.SetVersion(version)
+				if _, _, err := dec.Decode(); err == nil {
+					t.Fatalf("mix %v version %d: expected error for %d bytes", mix, version, i)
+				}
+				_, _, _ = s.UnmarshalVersion(raw[:i], version)
+			}
+		}
+	}
+}
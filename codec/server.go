@@ -2,17 +2,27 @@
 package codec
 
 import (
-	"bytes"
 	"encoding/binary"
 	"github.com/cbwfree/micro-game/utils/errors"
 )
 
 // ServerHead 服务器消息头
 type ServerHead struct {
-	Serial  uint16
-	Cmd     uint32
-	Code    uint32
-	DataLen uint32
+	Serial   uint16
+	Cmd      uint32
+	Code     uint32
+	DataLen  uint32
+	Version  uint8  // 消息头版本 (为0时使用旧版消息头)
+	Flags    uint8  // 消息标记位 (仅新版消息头)
+	Checksum uint32 // CRC32校验值 (Flags 包含 FlagChecksum 时有效)
+}
+
+// Verify 校验消息数据
+func (h *ServerHead) Verify(data []byte) error {
+	if h.Flags&FlagChecksum != 0 && checksum(data) != h.Checksum {
+		return errors.Invalid("msg checksum error")
+	}
+	return nil
 }
 
 // Client 服务端消息
@@ -23,10 +33,22 @@ type Server struct {
 	headLen int              // 消息头长度
 }
 
+// HeadLen 旧版消息头长度 (读取消息时至少需要读取的长度)
 func (c *Server) HeadLen() int {
 	return c.headLen + c.mixLen
 }
 
+// HeadLenOfVersion 按指定版本计算完整的消息头长度
+func (c *Server) HeadLenOfVersion(prefix []byte, version uint8) (int, error) {
+	if len(prefix) < c.HeadLen() {
+		return 0, errors.Invalid("msg head length error")
+	}
+	if version != Version2 {
+		return c.HeadLen(), nil
+	}
+	return c.mixLen + headLenV2(serverHeadLenV2, prefix[c.mixLen+1]), nil
+}
+
 // 设置混淆 (最长4位)
 func (c *Server) SetMix(mix ...uint8) {
 	c.mixHead = mix
@@ -38,69 +60,113 @@ func (c *Server) SetByteOrder(bin binary.ByteOrder) {
 	c.bin = bin
 }
 
-// Marshal 编码消息
-func (c *Server) Marshal(head *ServerHead, data []byte) (b []byte, err error) {
-	var buf = new(bytes.Buffer)
-	var msgLen = len(data)
-
-	if c.mixLen > 0 {
-		for i := 0; i < c.mixLen; i++ {
-			err = binary.Write(buf, c.bin, c.mixHead[i])
-		}
+// Marshal 编码消息 (head.Version 为 Version2 时使用新版消息头)
+func (c *Server) Marshal(head *ServerHead, data []byte) ([]byte, error) {
+	b := make([]byte, 0, c.mixLen+serverHeadLenV2+checksumLen+len(data))
+	b, err := c.appendHead(b, head, data)
+	if err != nil {
+		return nil, err
 	}
+	return append(b, data...), nil
+}
 
-	err = binary.Write(buf, c.bin, head.Serial)
-	err = binary.Write(buf, c.bin, head.Cmd)
-	err = binary.Write(buf, c.bin, head.Code)
-	err = binary.Write(buf, c.bin, uint32(msgLen))
-	err = binary.Write(buf, c.bin, data)
+// 编码消息头 (含混淆头)
+func (c *Server) appendHead(b []byte, head *ServerHead, data []byte) ([]byte, error) {
+	if err := checkHead(head.Version, len(data)); err != nil {
+		return nil, err
+	}
 
-	b = buf.Bytes()
+	b = append(b, c.mixHead...)
+	if head.Version == Version2 {
+		b = append(b, VersionMarker, head.Flags)
+	}
+	b = appendUint16(c.bin, b, head.Serial)
+	b = appendUint32(c.bin, b, head.Cmd)
+	b = appendUint32(c.bin, b, head.Code)
+	b = appendUint32(c.bin, b, uint32(len(data)))
+	if head.Version == Version2 && head.Flags&FlagChecksum != 0 {
+		b = appendUint32(c.bin, b, checksum(data))
+	}
 
-	return b, err
+	return b, nil
 }
 
-// Unmarshal 解码消息
+// Unmarshal 解码旧版消息 (协商使用新版消息头时使用 UnmarshalVersion)
 func (c *Server) Unmarshal(raw []byte) (head *ServerHead, data []byte, err error) {
-	var rawLen = len(raw)
-	if rawLen < c.headLen {
-		return nil, nil, errors.Invalid("msg head length error")
+	return c.UnmarshalVersion(raw, Version1)
+}
+
+// UnmarshalVersion 按指定版本解码消息
+// 	raw 仅包含消息头时, 只解析消息头
+func (c *Server) UnmarshalVersion(raw []byte, version uint8) (head *ServerHead, data []byte, err error) {
+	head, headLen, err := c.parseHead(raw, version)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 校验head
-	if c.mixLen > 0 {
-		heads := raw[:c.mixLen]
-		raw = raw[c.mixLen:]
-		rawLen = rawLen - c.mixLen
-
-		for i, head := range heads {
-			if c.mixHead[i] != head {
-				return nil, nil, errors.Invalid("msg head check error")
-			}
+	if len(raw) > headLen {
+		var maxLen = int(head.DataLen) + headLen
+		if len(raw) < maxLen {
+			return nil, nil, errors.Invalid("msg data length error")
+		}
+		data = raw[headLen:maxLen]
+		if err := head.Verify(data); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	head = new(ServerHead)
-	head.Serial = c.bin.Uint16(raw[:2])
-	head.Cmd = c.bin.Uint32(raw[2:6])
-	head.Code = c.bin.Uint32(raw[6:10])
-	head.DataLen = c.bin.Uint32(raw[10:c.headLen])
+	return head, data, nil
+}
+
+// 解析消息头, 返回消息头长度 (含混淆头)
+func (c *Server) parseHead(raw []byte, version uint8) (*ServerHead, int, error) {
+	if len(raw) < c.HeadLen() {
+		return nil, 0, errors.Invalid("msg head length error")
+	}
 
-	if rawLen > c.headLen {
-		var maxLen = int(head.DataLen) + c.headLen
-		if rawLen < maxLen {
-			return nil, nil, errors.Invalid("msg data length error")
+	// 校验head
+	for i := 0; i < c.mixLen; i++ {
+		if c.mixHead[i] != raw[i] {
+			return nil, 0, errors.Invalid("msg head check error")
 		}
-		data = raw[c.headLen:maxLen]
+	}
+	raw = raw[c.mixLen:]
+
+	if version != Version2 {
+		head := &ServerHead{Version: Version1}
+		head.Serial = c.bin.Uint16(raw[:2])
+		head.Cmd = c.bin.Uint32(raw[2:6])
+		head.Code = c.bin.Uint32(raw[6:10])
+		head.DataLen = c.bin.Uint32(raw[10:c.headLen])
+		return head, c.HeadLen(), nil
 	}
 
-	return head, data, nil
+	if raw[0] != VersionMarker {
+		return nil, 0, errors.Invalid("msg head version error")
+	}
+
+	head := &ServerHead{Version: Version2}
+	head.Flags = raw[1]
+	headLen := headLenV2(serverHeadLenV2, head.Flags)
+	if len(raw) < headLen {
+		return nil, 0, errors.Invalid("msg head length error")
+	}
+
+	head.Serial = c.bin.Uint16(raw[2:4])
+	head.Cmd = c.bin.Uint32(raw[4:8])
+	head.Code = c.bin.Uint32(raw[8:12])
+	head.DataLen = c.bin.Uint32(raw[12:16])
+	if head.Flags&FlagChecksum != 0 {
+		head.Checksum = c.bin.Uint32(raw[16:headLen])
+	}
+
+	return head, c.mixLen + headLen, nil
 }
 
 func NewBinServer(bin binary.ByteOrder, mix ...uint8) *Server {
 	c := &Server{
 		bin:     bin,
-		headLen: 14,
+		headLen: serverHeadLenV1,
 	}
 	c.SetMix(mix...)
 	return c
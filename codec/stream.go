@@ -0,0 +1,297 @@
+package codec
+
+import (
+	"github.com/cbwfree/micro-game/utils/errors"
+	"io"
+	"sync"
+)
+
+var (
+	DefaultMaxDataLen uint32 = 4 << 20  // 默认单个消息最大数据长度
+	DefaultBufSize           = 4 << 10  // 默认缓冲区大小
+	maxPoolBufSize           = 64 << 10 // 超过该大小的缓冲区不放回缓冲池
+)
+
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, DefaultBufSize)
+		return &b
+	},
+}
+
+func getBuf() *[]byte {
+	return bufPool.Get().(*[]byte)
+}
+
+func putBuf(b *[]byte) {
+	if cap(*b) > maxPoolBufSize {
+		return
+	}
+	*b = (*b)[:0]
+	bufPool.Put(b)
+}
+
+// 流式读取缓冲区
+type reader struct {
+	r       io.Reader
+	buf     *[]byte
+	version uint8  // 消息头版本 (默认旧版消息头, 协商新版消息头后通过 SetVersion 指定)
+	maxLen  uint32 // 最大数据长度
+	size    int    // 最近读取的消息长度 (含消息头)
+}
+
+// 读取数据到缓冲区的 [from, to) 区间
+func (r *reader) fill(from, to int) error {
+	if r.buf == nil {
+		return errors.Invalid("decoder is released")
+	}
+	if cap(*r.buf) < to {
+		b := make([]byte, from, to)
+		copy(b, (*r.buf)[:from])
+		putBuf(r.buf)
+		r.buf = &b
+	}
+	*r.buf = (*r.buf)[:to]
+	_, err := io.ReadFull(r.r, (*r.buf)[from:to])
+	return err
+}
+
+// 读取消息头, 返回消息头数据
+func (r *reader) readHead(minLen int, fullLen func([]byte, uint8) (int, error)) ([]byte, error) {
+	if err := r.fill(0, minLen); err != nil {
+		return nil, err
+	}
+	n, err := fullLen(*r.buf, r.version)
+	if err != nil {
+		return nil, err
+	}
+	if n > minLen {
+		if err := r.fill(minLen, n); err != nil {
+			return nil, err
+		}
+	}
+	return *r.buf, nil
+}
+
+// 读取消息数据 (返回的切片引用缓冲区, 在下次读取前有效)
+func (r *reader) readData(headLen int, dataLen uint32) ([]byte, error) {
+	if dataLen > r.maxLen {
+		return nil, errors.Invalid("msg data too large: %d", dataLen)
+	}
+	to := headLen + int(dataLen)
+	if err := r.fill(headLen, to); err != nil {
+		return nil, err
+	}
+	r.size = to
+	return (*r.buf)[headLen:to:to], nil
+}
+
+func (r *reader) release() {
+	if r.buf != nil {
+		putBuf(r.buf)
+		r.buf = nil
+	}
+}
+
+func newReader(rd io.Reader) reader {
+	return reader{
+		r:      rd,
+		buf:     getBuf(),
+		version: Version1,
+		maxLen:  DefaultMaxDataLen,
+	}
+}
+
+// Decoder 客户端消息流式解码 (网关读取客户端消息)
+type Decoder struct {
+	reader
+	codec *Client
+}
+
+// Version 消息头版本
+func (d *Decoder) Version() uint8 {
+	return d.version
+}
+
+// SetVersion 固定消息头版本
+func (d *Decoder) SetVersion(version uint8) {
+	d.version = version
+}
+
+// Size 最近读取的消息长度 (含消息头)
+func (d *Decoder) Size() int {
+	return d.size
+}
+
+// SetMaxDataLen 设置单个消息最大数据长度
+func (d *Decoder) SetMaxDataLen(n uint32) {
+	d.maxLen = n
+}
+
+// Reset 重置数据源 (保留消息头版本, 如 WebSocket 每个消息使用独立的 Reader)
+func (d *Decoder) Reset(r io.Reader) {
+	d.r = r
+}
+
+// Decode 读取一个消息
+// 	返回的数据引用内部缓冲区, 在下次调用 Decode 前有效
+func (d *Decoder) Decode() (*ClientHead, []byte, error) {
+	raw, err := d.readHead(d.codec.HeadLen(), d.codec.HeadLenOfVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	head, headLen, err := d.codec.parseHead(raw, d.version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := d.readData(headLen, head.DataLen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := head.Verify(data); err != nil {
+		return nil, nil, err
+	}
+
+	return head, data, nil
+}
+
+// Release 释放缓冲区 (释放后不能再使用)
+func (d *Decoder) Release() {
+	d.release()
+}
+
+func NewDecoder(r io.Reader, c *Client) *Decoder {
+	return &Decoder{
+		reader: newReader(r),
+		codec:  c,
+	}
+}
+
+// ServerDecoder 服务端消息流式解码 (客户端读取服务端消息)
+type ServerDecoder struct {
+	reader
+	codec *Server
+}
+
+// Version 消息头版本
+func (d *ServerDecoder) Version() uint8 {
+	return d.version
+}
+
+// SetVersion 固定消息头版本
+func (d *ServerDecoder) SetVersion(version uint8) {
+	d.version = version
+}
+
+// Size 最近读取的消息长度 (含消息头)
+func (d *ServerDecoder) Size() int {
+	return d.size
+}
+
+// SetMaxDataLen 设置单个消息最大数据长度
+func (d *ServerDecoder) SetMaxDataLen(n uint32) {
+	d.maxLen = n
+}
+
+// Reset 重置数据源
+func (d *ServerDecoder) Reset(r io.Reader) {
+	d.r = r
+}
+
+// Decode 读取一个消息
+// 	返回的数据引用内部缓冲区, 在下次调用 Decode 前有效
+func (d *ServerDecoder) Decode() (*ServerHead, []byte, error) {
+	raw, err := d.readHead(d.codec.HeadLen(), d.codec.HeadLenOfVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	head, headLen, err := d.codec.parseHead(raw, d.version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := d.readData(headLen, head.DataLen)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := head.Verify(data); err != nil {
+		return nil, nil, err
+	}
+
+	return head, data, nil
+}
+
+// Release 释放缓冲区 (释放后不能再使用)
+func (d *ServerDecoder) Release() {
+	d.release()
+}
+
+func NewServerDecoder(r io.Reader, c *Server) *ServerDecoder {
+	return &ServerDecoder{
+		reader: newReader(r),
+		codec:  c,
+	}
+}
+
+// Encoder 服务端消息流式编码 (网关发送消息到客户端)
+type Encoder struct {
+	w     io.Writer
+	codec *Server
+}
+
+// Encode 编码并写入一个消息
+func (e *Encoder) Encode(head *ServerHead, data []byte) error {
+	buf := getBuf()
+	defer putBuf(buf)
+
+	b, err := e.codec.appendHead(*buf, head, data)
+	if err != nil {
+		return err
+	}
+	b = append(b, data...)
+	*buf = b
+
+	_, err = e.w.Write(b)
+	return err
+}
+
+func NewEncoder(w io.Writer, c *Server) *Encoder {
+	return &Encoder{
+		w:     w,
+		codec: c,
+	}
+}
+
+// ClientEncoder 客户端消息流式编码 (客户端发送消息到网关)
+type ClientEncoder struct {
+	w     io.Writer
+	codec *Client
+}
+
+// Encode 编码并写入一个消息
+func (e *ClientEncoder) Encode(head *ClientHead, data []byte) error {
+	buf := getBuf()
+	defer putBuf(buf)
+
+	b, err := e.codec.appendHead(*buf, head, data)
+	if err != nil {
+		return err
+	}
+	b = append(b, data...)
+	*buf = b
+
+	_, err = e.w.Write(b)
+	return err
+}
+
+func NewClientEncoder(w io.Writer, c *Client) *ClientEncoder {
+	return &ClientEncoder{
+		w:     w,
+		codec: c,
+	}
+}
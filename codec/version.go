@@ -0,0 +1,71 @@
+package codec
+
+import (
+	"encoding/binary"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"hash/crc32"
+	"math"
+)
+
+// 消息头版本
+const (
+	Version1 uint8 = 1 // 旧版消息头 (客户端10字节, 服务端14字节)
+	Version2 uint8 = 2 // 带版本标识及标记位的消息头
+
+	// 新版消息头标识 (位于混淆头之后)
+	// 	该位置在旧版消息头中是 Serial 的首字节 (大端为高位字节, 小端为低位字节), 旧版消息可能与标识相同
+	// 	因此只能用于已知版本时的校验, 网关连接的消息头版本通过握手协商 (agent.CmdHandshake)
+	VersionMarker uint8 = 0xF2
+)
+
+// 消息标记位 (仅新版消息头)
+const (
+	FlagCompress uint8 = 1 << iota // 数据已压缩
+	FlagEncrypt                    // 数据已加密
+	FlagPush                       // 服务器推送消息 (非请求响应)
+	FlagChecksum                   // 附带CRC32校验
+)
+
+const (
+	clientHeadLenV1 = 10 // Serial(2) Cmd(4) DataLen(4)
+	serverHeadLenV1 = 14 // Serial(2) Cmd(4) Code(4) DataLen(4)
+	clientHeadLenV2 = 12 // Marker(1) Flags(1) Serial(2) Cmd(4) DataLen(4)
+	serverHeadLenV2 = 16 // Marker(1) Flags(1) Serial(2) Cmd(4) Code(4) DataLen(4)
+	checksumLen     = 4  // CRC32
+)
+
+// 计算新版消息头长度 (不含混淆头)
+func headLenV2(base int, flags uint8) int {
+	if flags&FlagChecksum != 0 {
+		return base + checksumLen
+	}
+	return base
+}
+
+// 计算CRC32校验值 (消息数据)
+func checksum(data []byte) uint32 {
+	return crc32.ChecksumIEEE(data)
+}
+
+// 检查消息头版本及数据长度
+func checkHead(version uint8, dataLen int) error {
+	if version > Version2 {
+		return errors.Invalid("unsupported msg head version: %d", version)
+	}
+	if uint64(dataLen) > math.MaxUint32 {
+		return errors.Invalid("msg data too large: %d", dataLen)
+	}
+	return nil
+}
+
+func appendUint16(bin binary.ByteOrder, b []byte, v uint16) []byte {
+	var buf [2]byte
+	bin.PutUint16(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(bin binary.ByteOrder, b []byte, v uint32) []byte {
+	var buf [4]byte
+	bin.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
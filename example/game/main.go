@@ -6,6 +6,7 @@ import (
 	"github.com/cbwfree/micro-game/example/game/protocol"
 	server "github.com/cbwfree/micro-game/example/game/rpc"
 	"github.com/cbwfree/micro-game/example/libs/def"
+	"github.com/cbwfree/micro-game/example/proto/router"
 	mgo "github.com/cbwfree/micro-game/store/mongo"
 	rds "github.com/cbwfree/micro-game/store/redis"
 	"github.com/cbwfree/micro-game/utils/log"
@@ -29,9 +30,9 @@ func main() {
 	)
 
 	// 注册游戏协议
-	mod.Router().AddRoute(
-		new(protocol.Login),
-	)
+	if err := router.RegisterLoginHandler(mod.Router(), new(protocol.Login)); err != nil {
+		log.Fatal("%+v", err)
+	}
 
 	// 启动服务
 	if err := app.Run(); err != nil {
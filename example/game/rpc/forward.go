@@ -6,6 +6,7 @@ import (
 	"github.com/cbwfree/micro-game/agent"
 	"github.com/cbwfree/micro-game/example/game/mod"
 	pgame "github.com/cbwfree/micro-game/example/proto/game"
+	"github.com/cbwfree/micro-game/protocol"
 	"github.com/cbwfree/micro-game/utils/color"
 	"github.com/cbwfree/micro-game/utils/debug"
 	"github.com/cbwfree/micro-game/utils/errors"
@@ -47,10 +48,14 @@ func (r *Forward) Protocol(ctx context.Context, req *pgame.InForwardProtocol, rs
 
 	// 请求协议路由
 	var status string
-	if s2c, err := mod.Router().Call(gmt, req.Cmd, req.Data); err != nil {
+	if s2c, err := mod.Router().CallCtx(ctx, gmt, req.Cmd, req.Data); err != nil {
 		ee := errors.Parse(err)
 		status = ee.Status
 		rsp.Code = uint32(ee.Code)
+		// 请求校验失败时返回字段错误 (JSON数组)
+		if protocol.ParseFieldErrors(err) != nil {
+			rsp.Data = []byte(ee.Detail)
+		}
 	} else {
 		rsp.Data = s2c
 	}
@@ -12,6 +12,7 @@ import (
 	rds "github.com/cbwfree/micro-game/store/redis"
 	"github.com/cbwfree/micro-game/utils/color"
 	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/cbwfree/micro-game/utils/trace"
 	"github.com/micro/go-micro/v2"
 	"github.com/pkg/errors"
 
@@ -57,6 +58,9 @@ func beforeStart() error {
 		return err
 	}
 
+	// 注册网关推送及认证服务
+	app.AddHandler(agent.NewGate(gate))
+
 	// 注册网关信息
 	app.AddMetadata(map[string]string{
 		"type":  agent.Opts.Type,
@@ -96,13 +100,7 @@ func (*GateService) Push(_ context.Context, req *pgate.InGatePush, _ *pgate.OutG
 		return errors.Errorf("client [%s] not found", req.ClientId)
 	}
 
-	b, err := gate.ServerCodec().Marshal(&codec.ServerHead{Cmd: req.Cmd, Code: req.Code}, req.Data)
-	if err != nil {
-		return err
-	}
-
-	client.Write(b)
-	return nil
+	return gate.Send(client, &codec.ServerHead{Cmd: req.Cmd, Code: req.Code, Version: client.Meta().CodecVer()}, req.Data)
 }
 
 // ---------------
@@ -121,8 +119,9 @@ func onReceiveHandler(client agent.Client, head *codec.ClientHead, data []byte)
 		Data: data,
 	}
 	out := new(pgame.OutForwardProtocol)
-	if err := app.CallCtx(client.Meta().Context(), def.SrvGameName, pgame.ForwardMethod_Protocol, in, out); err != nil {
-		client.Log().Warn(color.Warn.Text("[OnReceive] Forward Protocol [%d] error: %s", head.Cmd, err))
+	ctx := client.Meta().RequestContext(head.Serial)
+	if err := app.CallCtx(ctx, def.SrvGameName, pgame.ForwardMethod_Protocol, in, out); err != nil {
+		client.Log().Warn(color.Warn.Text("[OnReceive] Forward Protocol [%d] trace [%s] error: %s", head.Cmd, trace.TraceId(ctx), err))
 		return nil, nil, err
 	}
 
@@ -133,7 +132,7 @@ func onReceiveHandler(client agent.Client, head *codec.ClientHead, data []byte)
 	}, out.Data, nil
 }
 
-// OnDisconnectHandler 连接断开时触发
+// OnDisconnectHandler 连接断开时触发 (网关已删除Meta缓存)
 func onDisconnectHandler(client agent.Client) {
 	if !client.Meta().IsOnline() {
 		return
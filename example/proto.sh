@@ -3,6 +3,9 @@
 # 游戏协议 (不能引用其它包)
 protoc --proto_path=./ --go_out=paths=source_relative:. ./proto/msg/*.proto
 
+# 协议路由 (生成协议号及静态路由表)
+protoc --proto_path=./ --proto_path=../ --go_out=paths=source_relative:. --route_out=paths=source_relative:. ./proto/router/*.proto
+
 # 公共模块 (不能含有 service)
 protoc --proto_path=./ --go_out=paths=source_relative:. ./proto/com/*.proto
 
@@ -0,0 +1,109 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.25.0
+// 	protoc        v3.11.4
+// source: proto/router/login.proto
+
+package router
+
+import (
+	msg "github.com/cbwfree/micro-game/example/proto/msg"
+	_ "github.com/cbwfree/micro-game/protoc-gen-route/route"
+	proto "github.com/golang/protobuf/proto"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+var File_proto_router_login_proto protoreflect.FileDescriptor
+
+var file_proto_router_login_proto_rawDesc = []byte{
+	0x0a, 0x18, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x72, 0x2f, 0x6c,
+	0x6f, 0x67, 0x69, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x72, 0x6f, 0x75, 0x74,
+	0x65, 0x72, 0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a,
+	0x22, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x2d, 0x67, 0x65, 0x6e, 0x2d, 0x72, 0x6f, 0x75, 0x74,
+	0x65, 0x2f, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x2f, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x1a, 0x15, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6d, 0x73, 0x67, 0x2f, 0x6c,
+	0x6f, 0x67, 0x69, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x32, 0xe2, 0x01, 0x0a, 0x05, 0x4c,
+	0x6f, 0x67, 0x69, 0x6e, 0x12, 0x34, 0x0a, 0x0b, 0x4c, 0x6f, 0x67, 0x69, 0x6e, 0x53, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x12, 0x0e, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x43, 0x32, 0x53, 0x5f, 0x31, 0x30,
+	0x30, 0x30, 0x31, 0x1a, 0x0e, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x53, 0x32, 0x43, 0x5f, 0x31, 0x30,
+	0x30, 0x30, 0x31, 0x22, 0x05, 0x88, 0xb2, 0x19, 0x91, 0x4e, 0x12, 0x33, 0x0a, 0x0a, 0x53, 0x65,
+	0x6c, 0x65, 0x63, 0x74, 0x52, 0x6f, 0x6c, 0x65, 0x12, 0x0e, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x43,
+	0x32, 0x53, 0x5f, 0x31, 0x30, 0x30, 0x30, 0x32, 0x1a, 0x0e, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x53,
+	0x32, 0x43, 0x5f, 0x31, 0x30, 0x30, 0x30, 0x32, 0x22, 0x05, 0x88, 0xb2, 0x19, 0x92, 0x4e, 0x12,
+	0x33, 0x0a, 0x0a, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x52, 0x6f, 0x6c, 0x65, 0x12, 0x0e, 0x2e,
+	0x6d, 0x73, 0x67, 0x2e, 0x43, 0x32, 0x53, 0x5f, 0x31, 0x30, 0x30, 0x30, 0x33, 0x1a, 0x0e, 0x2e,
+	0x6d, 0x73, 0x67, 0x2e, 0x53, 0x32, 0x43, 0x5f, 0x31, 0x30, 0x30, 0x30, 0x33, 0x22, 0x05, 0x88,
+	0xb2, 0x19, 0x93, 0x4e, 0x12, 0x39, 0x0a, 0x08, 0x4e, 0x6f, 0x52, 0x65, 0x74, 0x75, 0x72, 0x6e,
+	0x12, 0x0e, 0x2e, 0x6d, 0x73, 0x67, 0x2e, 0x43, 0x32, 0x53, 0x5f, 0x31, 0x30, 0x30, 0x30, 0x34,
+	0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x22, 0x05, 0x88, 0xb2, 0x19, 0x94, 0x4e, 0x42,
+	0x34, 0x5a, 0x32, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x62,
+	0x77, 0x66, 0x72, 0x65, 0x65, 0x2f, 0x6d, 0x69, 0x63, 0x72, 0x6f, 0x2d, 0x67, 0x61, 0x6d, 0x65,
+	0x2f, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x72,
+	0x6f, 0x75, 0x74, 0x65, 0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var file_proto_router_login_proto_goTypes = []interface{}{
+	(*msg.C2S_10001)(nil), // 0: msg.C2S_10001
+	(*msg.C2S_10002)(nil), // 1: msg.C2S_10002
+	(*msg.C2S_10003)(nil), // 2: msg.C2S_10003
+	(*msg.C2S_10004)(nil), // 3: msg.C2S_10004
+	(*msg.S2C_10001)(nil), // 4: msg.S2C_10001
+	(*msg.S2C_10002)(nil), // 5: msg.S2C_10002
+	(*msg.S2C_10003)(nil), // 6: msg.S2C_10003
+	(*empty.Empty)(nil),   // 7: google.protobuf.Empty
+}
+var file_proto_router_login_proto_depIdxs = []int32{
+	0, // 0: router.Login.LoginServer:input_type -> msg.C2S_10001
+	1, // 1: router.Login.SelectRole:input_type -> msg.C2S_10002
+	2, // 2: router.Login.CreateRole:input_type -> msg.C2S_10003
+	3, // 3: router.Login.NoReturn:input_type -> msg.C2S_10004
+	4, // 4: router.Login.LoginServer:output_type -> msg.S2C_10001
+	5, // 5: router.Login.SelectRole:output_type -> msg.S2C_10002
+	6, // 6: router.Login.CreateRole:output_type -> msg.S2C_10003
+	7, // 7: router.Login.NoReturn:output_type -> google.protobuf.Empty
+	4, // [4:8] is the sub-list for method output_type
+	0, // [0:4] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_proto_router_login_proto_init() }
+func file_proto_router_login_proto_init() {
+	if File_proto_router_login_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_proto_router_login_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   0,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_router_login_proto_goTypes,
+		DependencyIndexes: file_proto_router_login_proto_depIdxs,
+	}.Build()
+	File_proto_router_login_proto = out.File
+	file_proto_router_login_proto_rawDesc = nil
+	file_proto_router_login_proto_goTypes = nil
+	file_proto_router_login_proto_depIdxs = nil
+}
@@ -3,10 +3,11 @@ module github.com/cbwfree/micro-game
 go 1.15
 
 require (
+	github.com/alicebob/miniredis/v2 v2.14.1
 	github.com/bsm/redislock v0.7.0
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/go-redis/redis/v8 v8.4.4
-	github.com/golang/protobuf v1.4.2
+	github.com/golang/protobuf v1.4.3
 	github.com/google/gops v0.3.14
 	github.com/google/uuid v1.1.2
 	github.com/gorilla/sessions v1.2.1
@@ -17,8 +18,11 @@ require (
 	github.com/lucas-clemente/quic-go v0.19.3
 	github.com/micro/cli/v2 v2.1.2
 	github.com/micro/go-micro/v2 v2.9.1
-	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.9.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/steambap/captcha v1.3.1
+	github.com/vmihailenco/msgpack/v5 v5.1.0
 	go.mongodb.org/mongo-driver v1.4.4
+	google.golang.org/grpc v1.26.0
 	google.golang.org/protobuf v1.23.0
 )
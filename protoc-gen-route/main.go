@@ -0,0 +1,197 @@
+// 游戏协议路由代码生成插件
+//
+// 读取 service 中通过 (route.cmd) 选项声明的协议号, 生成协议号常量、处理接口、
+// 静态路由注册函数以及基于 protocol.Router 的本地调用器, 调用时无需反射.
+//
+// 	protoc --proto_path=./ --proto_path=../ --go_out=paths=source_relative:. --route_out=paths=source_relative:. ./proto/router/*.proto
+//
+// 使用 --route_out=ctx=true,paths=source_relative:. 时, 处理接口使用 context.Context 签名
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/cbwfree/micro-game/protoc-gen-route/route"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	contextPackage  = protogen.GoImportPath("context")
+	agentPackage    = protogen.GoImportPath("github.com/cbwfree/micro-game/agent")
+	protocolPackage = protogen.GoImportPath("github.com/cbwfree/micro-game/protocol")
+	protoPackage    = protogen.GoImportPath("github.com/golang/protobuf/proto")
+)
+
+var (
+	flags   flag.FlagSet
+	withCtx = flags.Bool("ctx", false, "generate handler interface with context.Context")
+)
+
+func main() {
+	protogen.Options{ParamFunc: flags.Set}.Run(func(gen *protogen.Plugin) error {
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			if err := generateFile(gen, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// 协议方法
+type routeMethod struct {
+	*protogen.Method
+	cmd uint32
+}
+
+// 解析方法上的协议号
+func methodCmd(m *protogen.Method) (uint32, bool) {
+	opts, ok := m.Desc.Options().(*descriptorpb.MethodOptions)
+	if !ok || opts == nil || !proto.HasExtension(opts, route.E_Cmd) {
+		return 0, false
+	}
+	return proto.GetExtension(opts, route.E_Cmd).(uint32), true
+}
+
+func generateFile(gen *protogen.Plugin, file *protogen.File) error {
+	var services = make(map[*protogen.Service][]*routeMethod)
+	var cmds = make(map[uint32]string)
+
+	for _, s := range file.Services {
+		for _, m := range s.Methods {
+			cmd, ok := methodCmd(m)
+			if !ok {
+				continue
+			}
+			if m.Desc.IsStreamingClient() || m.Desc.IsStreamingServer() {
+				return fmt.Errorf("%s: streaming method is not supported", m.Desc.FullName())
+			}
+			if name, ok := cmds[cmd]; ok {
+				return fmt.Errorf("%s: duplicate command %d with %s", m.Desc.FullName(), cmd, name)
+			}
+			cmds[cmd] = string(m.Desc.FullName())
+			services[s] = append(services[s], &routeMethod{Method: m, cmd: cmd})
+		}
+	}
+	if len(services) == 0 {
+		return nil
+	}
+
+	filename := file.GeneratedFilenamePrefix + ".pb.route.go"
+	g := gen.NewGeneratedFile(filename, file.GoImportPath)
+	g.P("// Code generated by protoc-gen-route. DO NOT EDIT.")
+	g.P("// source: ", file.Desc.Path())
+	g.P()
+	g.P("package ", file.GoPackageName)
+	g.P()
+
+	for _, s := range file.Services {
+		if methods, ok := services[s]; ok {
+			generateService(g, s, methods)
+		}
+	}
+
+	return nil
+}
+
+func generateService(g *protogen.GeneratedFile, s *protogen.Service, methods []*routeMethod) {
+	name := s.GoName
+
+	// 协议号常量
+	g.P("// ", name, " 协议号")
+	g.P("const (")
+	for _, m := range methods {
+		g.P(cmdConst(name, m), " uint32 = ", m.cmd)
+	}
+	g.P(")")
+	g.P()
+
+	// 处理接口
+	g.P("// ", name, "Handler 协议处理接口")
+	g.P("type ", name, "Handler interface {")
+	for _, m := range methods {
+		g.P(m.GoName, "(", firstArg(g), ", c2s *", g.QualifiedGoIdent(m.Input.GoIdent), ", s2c *", g.QualifiedGoIdent(m.Output.GoIdent), ") error")
+	}
+	g.P("}")
+	g.P()
+
+	// 静态路由表
+	g.P("// ", name, "Routes 协议路由表")
+	g.P("func ", name, "Routes(h ", name, "Handler) []*", g.QualifiedGoIdent(protocolPackage.Ident("Route")), " {")
+	g.P("return []*", g.QualifiedGoIdent(protocolPackage.Ident("Route")), "{")
+	for _, m := range methods {
+		in := g.QualifiedGoIdent(m.Input.GoIdent)
+		out := g.QualifiedGoIdent(m.Output.GoIdent)
+		msg := g.QualifiedGoIdent(protoPackage.Ident("Message"))
+		g.P(g.QualifiedGoIdent(protocolPackage.Ident("NewRoute")), "(", cmdConst(name, m), ", \"", name, ".", m.GoName, "\",")
+		g.P("func() ", msg, " { return new(", in, ") },")
+		g.P("func() ", msg, " { return new(", out, ") },")
+		g.P("func(ctx ", g.QualifiedGoIdent(contextPackage.Ident("Context")), ", gmt *", g.QualifiedGoIdent(agentPackage.Ident("Meta")), ", req, rsp ", msg, ") error {")
+		if *withCtx {
+			g.P("return h.", m.GoName, "(ctx, req.(*", in, "), rsp.(*", out, "))")
+		} else {
+			g.P("return h.", m.GoName, "(gmt, req.(*", in, "), rsp.(*", out, "))")
+		}
+		g.P("},")
+		g.P("),")
+	}
+	g.P("}")
+	g.P("}")
+	g.P()
+
+	// 注册函数
+	g.P("// Register", name, "Handler 注册协议处理")
+	g.P("func Register", name, "Handler(r *", g.QualifiedGoIdent(protocolPackage.Ident("Router")), ", h ", name, "Handler) error {")
+	g.P("return r.Handle(", name, "Routes(h)...)")
+	g.P("}")
+	g.P()
+
+	// 本地调用器
+	g.P("// ", name, "Invoker 协议本地调用器 (通过 protocol.Router 在进程内直接调用处理, 不经过网关及RPC)")
+	g.P("type ", name, "Invoker struct {")
+	g.P("r *", g.QualifiedGoIdent(protocolPackage.Ident("Router")))
+	g.P("}")
+	g.P()
+	for _, m := range methods {
+		in := g.QualifiedGoIdent(m.Input.GoIdent)
+		out := g.QualifiedGoIdent(m.Output.GoIdent)
+		g.P("// ", m.GoName, " [", m.cmd, "]")
+		if *withCtx {
+			g.P("func (c *", name, "Invoker) ", m.GoName, "(ctx ", g.QualifiedGoIdent(contextPackage.Ident("Context")), ", gmt *", g.QualifiedGoIdent(agentPackage.Ident("Meta")), ", in *", in, ") (*", out, ", error) {")
+			g.P("out := new(", out, ")")
+			g.P("err := c.r.InvokeCtx(ctx, gmt, ", cmdConst(name, m), ", in, out)")
+		} else {
+			g.P("func (c *", name, "Invoker) ", m.GoName, "(gmt *", g.QualifiedGoIdent(agentPackage.Ident("Meta")), ", in *", in, ") (*", out, ", error) {")
+			g.P("out := new(", out, ")")
+			g.P("err := c.r.Invoke(gmt, ", cmdConst(name, m), ", in, out)")
+		}
+		g.P("return out, err")
+		g.P("}")
+		g.P()
+	}
+	g.P("// New", name, "Invoker")
+	g.P("func New", name, "Invoker(r *", g.QualifiedGoIdent(protocolPackage.Ident("Router")), ") *", name, "Invoker {")
+	g.P("return &", name, "Invoker{")
+	g.P("r: r,")
+	g.P("}")
+	g.P("}")
+	g.P()
+}
+
+// 处理方法的第一个参数
+func firstArg(g *protogen.GeneratedFile) string {
+	if *withCtx {
+		return "ctx " + g.QualifiedGoIdent(contextPackage.Ident("Context"))
+	}
+	return "gmt *" + g.QualifiedGoIdent(agentPackage.Ident("Meta"))
+}
+
+// 协议号常量名称
+func cmdConst(service string, m *routeMethod) string {
+	return fmt.Sprintf("Cmd_%s_%s", service, m.GoName)
+}
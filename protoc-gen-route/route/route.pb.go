@@ -0,0 +1,100 @@
+// 游戏协议路由选项
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.25.0
+// 	protoc        v3.11.4
+// source: protoc-gen-route/route/route.proto
+
+package route
+
+import (
+	proto "github.com/golang/protobuf/proto"
+	descriptor "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+var file_protoc_gen_route_route_route_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptor.MethodOptions)(nil),
+		ExtensionType: (*uint32)(nil),
+		Field:         52001,
+		Name:          "route.cmd",
+		Tag:           "varint,52001,opt,name=cmd",
+		Filename:      "protoc-gen-route/route/route.proto",
+	},
+}
+
+// Extension fields to descriptor.MethodOptions.
+var (
+	// optional uint32 cmd = 52001;
+	E_Cmd = &file_protoc_gen_route_route_route_proto_extTypes[0] // 协议号
+)
+
+var File_protoc_gen_route_route_route_proto protoreflect.FileDescriptor
+
+var file_protoc_gen_route_route_route_proto_rawDesc = []byte{
+	0x0a, 0x22, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x2d, 0x67, 0x65, 0x6e, 0x2d, 0x72, 0x6f, 0x75,
+	0x74, 0x65, 0x2f, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x2f, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x1a, 0x20, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x65, 0x73,
+	0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x3a, 0x32, 0x0a,
+	0x03, 0x63, 0x6d, 0x64, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f, 0x70, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x18, 0xa1, 0x96, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x63, 0x6d,
+	0x64, 0x42, 0x36, 0x5a, 0x34, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x63, 0x62, 0x77, 0x66, 0x72, 0x65, 0x65, 0x2f, 0x6d, 0x69, 0x63, 0x72, 0x6f, 0x2d, 0x67, 0x61,
+	0x6d, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x2d, 0x67, 0x65, 0x6e, 0x2d, 0x72, 0x6f,
+	0x75, 0x74, 0x65, 0x2f, 0x72, 0x6f, 0x75, 0x74, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var file_protoc_gen_route_route_route_proto_goTypes = []interface{}{
+	(*descriptor.MethodOptions)(nil), // 0: google.protobuf.MethodOptions
+}
+var file_protoc_gen_route_route_route_proto_depIdxs = []int32{
+	0, // 0: route.cmd:extendee -> google.protobuf.MethodOptions
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_protoc_gen_route_route_route_proto_init() }
+func file_protoc_gen_route_route_route_proto_init() {
+	if File_protoc_gen_route_route_route_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_protoc_gen_route_route_route_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   0,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_protoc_gen_route_route_route_proto_goTypes,
+		DependencyIndexes: file_protoc_gen_route_route_route_proto_depIdxs,
+		ExtensionInfos:    file_protoc_gen_route_route_route_proto_extTypes,
+	}.Build()
+	File_protoc_gen_route_route_route_proto = out.File
+	file_protoc_gen_route_route_route_proto_rawDesc = nil
+	file_protoc_gen_route_route_route_proto_goTypes = nil
+	file_protoc_gen_route_route_route_proto_depIdxs = nil
+}
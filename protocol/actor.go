@@ -0,0 +1,194 @@
+package protocol
+
+import (
+	"context"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/log"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultActorQueueSize   = 64              // 默认邮箱队列长度
+	DefaultActorIdleTimeout = 5 * time.Minute // 默认邮箱空闲回收时间
+)
+
+// 角色邮箱参数
+type ActorOptions struct {
+	QueueSize   int           // 邮箱队列长度 (队列已满时拒绝执行)
+	IdleTimeout time.Duration // 邮箱空闲回收时间
+}
+
+type actorKey struct{}
+
+// 角色邮箱
+type mailbox struct {
+	roleId int64
+	tasks  chan func()
+}
+
+// Actors 角色邮箱管理 (同一角色的任务在其邮箱协程中串行执行)
+type Actors struct {
+	sync.Mutex
+	opts   *ActorOptions
+	boxes  map[int64]*mailbox
+	closed bool
+}
+
+// Len 当前邮箱数量
+func (a *Actors) Len() int {
+	a.Lock()
+	defer a.Unlock()
+
+	return len(a.boxes)
+}
+
+// Post 投递任务到角色邮箱 (异步执行, 用于离线通知、定时器等)
+func (a *Actors) Post(roleId int64, fn func()) error {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.closed {
+		return errors.Unavailable("actors is closed")
+	}
+
+	box, ok := a.boxes[roleId]
+	if !ok {
+		box = &mailbox{
+			roleId: roleId,
+			tasks:  make(chan func(), a.opts.QueueSize),
+		}
+		a.boxes[roleId] = box
+		go a.run(box)
+	}
+
+	// 在锁内投递, 保证邮箱回收时不会丢失任务
+	select {
+	case box.tasks <- fn:
+		return nil
+	default:
+		return errors.Unavailable("role %d mailbox is full", roleId)
+	}
+}
+
+// Do 在角色邮箱中执行任务, 并等待执行完成 (返回任务的错误)
+// 	在角色邮箱内再次调用时 (ctx 来自邮箱任务) 直接执行, 避免死锁
+// 	ctx 超时返回后, 已开始执行的任务会继续执行完成, 其错误被丢弃
+// 	任务的结果应只通过返回值传递, 不要写入调用方在超时后仍会使用的变量
+func (a *Actors) Do(ctx context.Context, roleId int64, fn func(ctx context.Context) error) error {
+	if id, ok := ctx.Value(actorKey{}).(int64); ok && id == roleId {
+		return fn(ctx)
+	}
+
+	done := make(chan error, 1)
+	err := a.Post(roleId, func() {
+		if err := ctx.Err(); err != nil {
+			done <- errors.Timeout("role %d mailbox: %s", roleId, err)
+			return
+		}
+		defer func() {
+			if v := recover(); v != nil {
+				done <- errors.Recover(v)
+				panic(v)
+			}
+		}()
+		done <- fn(context.WithValue(ctx, actorKey{}, roleId))
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Timeout("role %d mailbox: %s", roleId, ctx.Err())
+	}
+}
+
+// AfterFunc 定时投递任务到角色邮箱
+func (a *Actors) AfterFunc(roleId int64, d time.Duration, fn func()) *time.Timer {
+	return time.AfterFunc(d, func() {
+		if err := a.Post(roleId, fn); err != nil {
+			log.Warn("[Actors] role %d timer post error: %s", roleId, err)
+		}
+	})
+}
+
+// Close 关闭全部邮箱 (已投递的任务会继续执行完成)
+func (a *Actors) Close() {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.closed {
+		return
+	}
+	a.closed = true
+
+	for id, box := range a.boxes {
+		close(box.tasks)
+		delete(a.boxes, id)
+	}
+}
+
+// 邮箱协程
+func (a *Actors) run(box *mailbox) {
+	idle := time.NewTimer(a.opts.IdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case fn, ok := <-box.tasks:
+			if !ok {
+				return
+			}
+			a.exec(box, fn)
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(a.opts.IdleTimeout)
+		case <-idle.C:
+			if a.evict(box) {
+				return
+			}
+			idle.Reset(a.opts.IdleTimeout)
+		}
+	}
+}
+
+// 执行任务 (捕获panic, 避免邮箱协程退出)
+func (a *Actors) exec(box *mailbox, fn func()) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.Error("[Actors] role %d task panic: %+v", box.roleId, errors.Recover(v))
+		}
+	}()
+	fn()
+}
+
+// 回收空闲邮箱
+func (a *Actors) evict(box *mailbox) bool {
+	a.Lock()
+	defer a.Unlock()
+
+	if len(box.tasks) > 0 {
+		return false
+	}
+	if a.boxes[box.roleId] == box {
+		delete(a.boxes, box.roleId)
+	}
+	return true
+}
+
+func NewActors(opts *ActorOptions) *Actors {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultActorQueueSize
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultActorIdleTimeout
+	}
+	return &Actors{
+		opts:  opts,
+		boxes: make(map[int64]*mailbox),
+	}
+}
@@ -0,0 +1,94 @@
+package protocol
+
+import (
+	"sync"
+	"time"
+)
+
+// 熔断参数
+type BreakerOptions struct {
+	Threshold int           // 触发熔断的panic次数
+	Window    time.Duration // 统计时间窗口
+	Cooldown  time.Duration // 熔断持续时间
+}
+
+// 协议熔断状态
+type breakerState struct {
+	count     int       // 时间窗口内的panic次数
+	start     time.Time // 时间窗口开始时间
+	openUntil time.Time // 熔断截止时间
+}
+
+// 协议熔断器 (按协议号统计panic次数)
+type breaker struct {
+	sync.Mutex
+	opts   *BreakerOptions
+	states map[uint32]*breakerState
+}
+
+// Allow 检查协议是否可以执行
+func (b *breaker) Allow(cmd uint32) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	s, ok := b.states[cmd]
+	if !ok {
+		return true
+	}
+
+	if s.openUntil.IsZero() {
+		return true
+	}
+
+	if time.Now().Before(s.openUntil) {
+		return false
+	}
+
+	// 熔断结束, 重新统计
+	delete(b.states, cmd)
+
+	return true
+}
+
+// Panic 记录一次panic, 返回是否触发熔断
+func (b *breaker) Panic(cmd uint32) bool {
+	b.Lock()
+	defer b.Unlock()
+
+	now := time.Now()
+	s, ok := b.states[cmd]
+	if !ok || now.Sub(s.start) > b.opts.Window {
+		s = &breakerState{start: now}
+		b.states[cmd] = s
+	}
+
+	s.count++
+	if s.count >= b.opts.Threshold && s.openUntil.IsZero() {
+		s.openUntil = now.Add(b.opts.Cooldown)
+		return true
+	}
+
+	return false
+}
+
+// State 获取熔断中的协议及截止时间
+func (b *breaker) State() map[uint32]time.Time {
+	b.Lock()
+	defer b.Unlock()
+
+	var now = time.Now()
+	var res = make(map[uint32]time.Time)
+	for cmd, s := range b.states {
+		if !s.openUntil.IsZero() && now.Before(s.openUntil) {
+			res[cmd] = s.openUntil
+		}
+	}
+	return res
+}
+
+func newBreaker(opts *BreakerOptions) *breaker {
+	return &breaker{
+		opts:   opts,
+		states: make(map[uint32]*breakerState),
+	}
+}
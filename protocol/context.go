@@ -0,0 +1,19 @@
+package protocol
+
+import (
+	"context"
+	"github.com/cbwfree/micro-game/agent"
+)
+
+type metaKey struct{}
+
+// NewContext 将网关Meta附加到上下文
+func NewContext(ctx context.Context, gmt *agent.Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, gmt)
+}
+
+// FromContext 从上下文获取网关Meta
+func FromContext(ctx context.Context) (*agent.Meta, bool) {
+	gmt, ok := ctx.Value(metaKey{}).(*agent.Meta)
+	return gmt, ok
+}
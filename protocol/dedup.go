@@ -0,0 +1,93 @@
+package protocol
+
+import (
+	"fmt"
+	"github.com/cbwfree/micro-game/agent"
+	"github.com/cbwfree/micro-game/store/memory"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultDedupWindow = 30 * time.Second // 默认去重时间窗口
+)
+
+// 去重参数
+type DedupOptions struct {
+	Window time.Duration // 去重时间窗口 (窗口内重复的请求直接返回缓存的响应)
+}
+
+// 执行中的请求
+type dedupCall struct {
+	done chan struct{}
+	rsp  []byte
+	err  error
+}
+
+// 请求去重缓存 (按 ClientId + Serial + Cmd 缓存成功的响应)
+type dedup struct {
+	sync.Mutex
+	opts    *DedupOptions
+	store   *memory.Store
+	calls   map[string]*dedupCall
+	purgeAt time.Time
+}
+
+// Do 执行请求, 窗口内重复的请求返回缓存的响应, 并发的重复请求等待首次执行的结果
+func (d *dedup) Do(gmt *agent.Meta, cmd uint32, fn func() ([]byte, error)) ([]byte, error) {
+	serial, ok := gmt.Serial()
+	if !ok || gmt.ClientId() == "" {
+		return fn()
+	}
+
+	key := fmt.Sprintf("%s:%d:%d", gmt.ClientId(), serial, cmd)
+
+	d.Lock()
+	if rs, err := d.store.Read(key); err == nil {
+		d.Unlock()
+		return rs[0].Value().([]byte), nil
+	}
+	if c, ok := d.calls[key]; ok {
+		d.Unlock()
+		<-c.done
+		return c.rsp, c.err
+	}
+	c := &dedupCall{done: make(chan struct{})}
+	d.calls[key] = c
+	d.Unlock()
+
+	defer func() {
+		d.Lock()
+		if c.err == nil {
+			_ = d.store.Set(key, c.rsp, d.opts.Window)
+		}
+		delete(d.calls, key)
+		d.purge()
+		d.Unlock()
+		close(c.done)
+	}()
+
+	c.rsp, c.err = fn()
+
+	return c.rsp, c.err
+}
+
+// 定期清理过期的缓存
+func (d *dedup) purge() {
+	if now := time.Now(); now.After(d.purgeAt) {
+		d.store.Purge()
+		d.purgeAt = now.Add(d.opts.Window)
+	}
+}
+
+func newDedup(opts *DedupOptions) *dedup {
+	if opts.Window <= 0 {
+		opts.Window = DefaultDedupWindow
+	}
+	return &dedup{
+		opts:    opts,
+		store:   memory.NewStore(),
+		calls:   make(map[string]*dedupCall),
+		purgeAt: time.Now().Add(opts.Window),
+	}
+}
@@ -0,0 +1,326 @@
+package protocol
+
+import (
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"math"
+	"strconv"
+)
+
+// MessagePack 编码 (直接读取消息字段, 保留数值类型)
+// 	消息编码为以proto字段名为键的map, 整数编码为msgpack整数, float/double 编码为 float32/float64
+// 	bytes 编码为 bin, 枚举编码为整数, 未设置的 optional/oneof/消息字段不输出
+func marshalMsgpack(enc *msgpack.Encoder, m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+
+	var list []protoreflect.FieldDescriptor
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if m.Has(fd) || !fd.HasPresence() {
+			list = append(list, fd)
+		}
+	}
+
+	if err := enc.EncodeMapLen(len(list)); err != nil {
+		return err
+	}
+	for _, fd := range list {
+		if err := enc.EncodeString(string(fd.Name())); err != nil {
+			return err
+		}
+		if err := marshalMsgpackField(enc, fd, m.Get(fd)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalMsgpackField(enc *msgpack.Encoder, fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		if err := enc.EncodeArrayLen(list.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < list.Len(); i++ {
+			if err := marshalMsgpackValue(enc, fd, list.Get(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case fd.IsMap():
+		mp := v.Map()
+		if err := enc.EncodeMapLen(mp.Len()); err != nil {
+			return err
+		}
+		var err error
+		mp.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			if err = marshalMsgpackValue(enc, fd.MapKey(), k.Value()); err != nil {
+				return false
+			}
+			err = marshalMsgpackValue(enc, fd.MapValue(), v)
+			return err == nil
+		})
+		return err
+	default:
+		return marshalMsgpackValue(enc, fd, v)
+	}
+}
+
+func marshalMsgpackValue(enc *msgpack.Encoder, fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return enc.EncodeBool(v.Bool())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return enc.EncodeInt(v.Int())
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return enc.EncodeUint(v.Uint())
+	case protoreflect.FloatKind:
+		return enc.EncodeFloat32(float32(v.Float()))
+	case protoreflect.DoubleKind:
+		return enc.EncodeFloat64(v.Float())
+	case protoreflect.StringKind:
+		return enc.EncodeString(v.String())
+	case protoreflect.BytesKind:
+		return enc.EncodeBytes(v.Bytes())
+	case protoreflect.EnumKind:
+		return enc.EncodeInt(int64(v.Enum()))
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return marshalMsgpack(enc, v.Message())
+	}
+	return fmt.Errorf("msgpack: unsupported field %s kind %s", fd.FullName(), fd.Kind())
+}
+
+// MessagePack 解码 (字段名支持proto名称及JSON名称, 未知字段及nil值忽略)
+func unmarshalMsgpack(dec *msgpack.Decoder, m protoreflect.Message) error {
+	if isMsgpackNil(dec) {
+		return dec.DecodeNil()
+	}
+
+	n, err := dec.DecodeMapLen()
+	if err != nil {
+		return err
+	}
+
+	fields := m.Descriptor().Fields()
+	for i := 0; i < n; i++ {
+		name, err := dec.DecodeString()
+		if err != nil {
+			return err
+		}
+
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil || isMsgpackNil(dec) {
+			if err := dec.Skip(); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := unmarshalMsgpackField(dec, m, fd); err != nil {
+			return fmt.Errorf("%s: %s", fd.FullName(), err)
+		}
+	}
+	return nil
+}
+
+func unmarshalMsgpackField(dec *msgpack.Decoder, m protoreflect.Message, fd protoreflect.FieldDescriptor) error {
+	switch {
+	case fd.IsList():
+		n, err := dec.DecodeArrayLen()
+		if err != nil {
+			return err
+		}
+		list := m.Mutable(fd).List()
+		for i := 0; i < n; i++ {
+			v, err := unmarshalMsgpackValue(dec, fd, list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+		return nil
+	case fd.IsMap():
+		n, err := dec.DecodeMapLen()
+		if err != nil {
+			return err
+		}
+		mp := m.Mutable(fd).Map()
+		for i := 0; i < n; i++ {
+			k, err := unmarshalMsgpackValue(dec, fd.MapKey(), nil)
+			if err != nil {
+				return err
+			}
+			v, err := unmarshalMsgpackValue(dec, fd.MapValue(), mp.NewValue)
+			if err != nil {
+				return err
+			}
+			mp.Set(k.MapKey(), v)
+		}
+		return nil
+	default:
+		v, err := unmarshalMsgpackValue(dec, fd, func() protoreflect.Value {
+			return m.NewField(fd)
+		})
+		if err != nil {
+			return err
+		}
+		m.Set(fd, v)
+		return nil
+	}
+}
+
+func unmarshalMsgpackValue(dec *msgpack.Decoder, fd protoreflect.FieldDescriptor, newValue func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := dec.DecodeBool()
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := decodeMsgpackInt(dec, math.MinInt32, math.MaxInt32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := decodeMsgpackInt(dec, math.MinInt64, math.MaxInt64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := decodeMsgpackUint(dec, math.MaxUint32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := decodeMsgpackUint(dec, math.MaxUint64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		f, err := dec.DecodeFloat64()
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := dec.DecodeFloat64()
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.StringKind:
+		s, err := dec.DecodeString()
+		return protoreflect.ValueOfString(s), err
+	case protoreflect.BytesKind:
+		b, err := dec.DecodeBytes()
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.EnumKind:
+		return decodeMsgpackEnum(dec, fd)
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		v := newValue()
+		return v, unmarshalMsgpack(dec, v.Message())
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported kind %s", fd.Kind())
+}
+
+// 整数 (兼容客户端以浮点数或字符串传递的整数, 如 JavaScript 的 number 及 int64 字符串)
+func decodeMsgpackInt(dec *msgpack.Decoder, min, max int64) (int64, error) {
+	c, err := dec.PeekCode()
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	switch {
+	case c == msgpcode.Float || c == msgpcode.Double:
+		f, err := dec.DecodeFloat64()
+		if err != nil {
+			return 0, err
+		}
+		if f != math.Trunc(f) || f < float64(min) || f > float64(max) {
+			return 0, fmt.Errorf("invalid integer %v", f)
+		}
+		n = int64(f)
+	case msgpcode.IsString(c):
+		s, err := dec.DecodeString()
+		if err != nil {
+			return 0, err
+		}
+		if n, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return 0, err
+		}
+	case c == msgpcode.Uint64:
+		u, err := dec.DecodeUint64()
+		if err != nil {
+			return 0, err
+		}
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("integer %d overflow", u)
+		}
+		n = int64(u)
+	default:
+		if n, err = dec.DecodeInt64(); err != nil {
+			return 0, err
+		}
+	}
+
+	if n < min || n > max {
+		return 0, fmt.Errorf("integer %d overflow", n)
+	}
+	return n, nil
+}
+
+// 无符号整数
+func decodeMsgpackUint(dec *msgpack.Decoder, max uint64) (uint64, error) {
+	c, err := dec.PeekCode()
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	switch {
+	case c == msgpcode.Uint8 || c == msgpcode.Uint16 || c == msgpcode.Uint32 || c == msgpcode.Uint64:
+		if n, err = dec.DecodeUint64(); err != nil {
+			return 0, err
+		}
+	case msgpcode.IsString(c):
+		s, err := dec.DecodeString()
+		if err != nil {
+			return 0, err
+		}
+		if n, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return 0, err
+		}
+	default:
+		i, err := decodeMsgpackInt(dec, 0, math.MaxInt64)
+		if err != nil {
+			return 0, err
+		}
+		n = uint64(i)
+	}
+
+	if n > max {
+		return 0, fmt.Errorf("integer %d overflow", n)
+	}
+	return n, nil
+}
+
+// 枚举 (支持整数或枚举名称)
+func decodeMsgpackEnum(dec *msgpack.Decoder, fd protoreflect.FieldDescriptor) (protoreflect.Value, error) {
+	c, err := dec.PeekCode()
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+
+	if msgpcode.IsString(c) {
+		s, err := dec.DecodeString()
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		ev := fd.Enum().Values().ByName(protoreflect.Name(s))
+		if ev == nil {
+			return protoreflect.Value{}, fmt.Errorf("invalid enum value %s", s)
+		}
+		return protoreflect.ValueOfEnum(ev.Number()), nil
+	}
+
+	n, err := decodeMsgpackInt(dec, math.MinInt32, math.MaxInt32)
+	return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), err
+}
+
+func isMsgpackNil(dec *msgpack.Decoder) bool {
+	c, err := dec.PeekCode()
+	return err == nil && c == msgpcode.Nil
+}
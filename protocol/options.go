@@ -0,0 +1,81 @@
+package protocol
+
+import (
+	"time"
+
+	"github.com/cbwfree/micro-game/app"
+)
+
+type Option func(o *Options)
+
+// 路由参数
+type Options struct {
+	Breaker    *BreakerOptions // 熔断设置 (为nil时不启用)
+	Serializer Serializer      // 默认序列化方式 (客户端未指定时使用)
+	Actor      *ActorOptions   // 角色邮箱设置 (为nil时不启用)
+	Dedup      *DedupOptions   // 请求去重设置 (为nil时不启用)
+	Service    *app.Service    // 所属服务 (推送消息及同步认证信息使用), 为空时使用默认服务
+}
+
+func (o *Options) Init(opts ...Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+// WithBreaker 启用协议熔断
+// 	@threshold 时间窗口内panic次数达到该值时熔断
+// 	@window 统计时间窗口
+// 	@cooldown 熔断持续时间
+func WithBreaker(threshold int, window, cooldown time.Duration) Option {
+	return func(o *Options) {
+		o.Breaker = &BreakerOptions{
+			Threshold: threshold,
+			Window:    window,
+			Cooldown:  cooldown,
+		}
+	}
+}
+
+// WithActor 启用角色邮箱模式, 同一角色的协议在其邮箱中串行执行
+// 	@queueSize 邮箱队列长度
+// 	@idleTimeout 邮箱空闲回收时间
+func WithActor(queueSize int, idleTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.Actor = &ActorOptions{
+			QueueSize:   queueSize,
+			IdleTimeout: idleTimeout,
+		}
+	}
+}
+
+// WithDedup 启用请求去重, 标记为幂等的协议在时间窗口内按请求序号只执行一次
+func WithDedup(window time.Duration) Option {
+	return func(o *Options) {
+		o.Dedup = &DedupOptions{
+			Window: window,
+		}
+	}
+}
+
+// WithSerializer 设置默认序列化方式
+func WithSerializer(s Serializer) Option {
+	return func(o *Options) {
+		o.Serializer = s
+	}
+}
+
+// WithService 设置所属服务 (同一进程运行多个服务时使用)
+func WithService(s *app.Service) Option {
+	return func(o *Options) {
+		o.Service = s
+	}
+}
+
+func newOptions(opts ...Option) *Options {
+	o := &Options{
+		Serializer: new(ProtoSerializer),
+	}
+	o.Init(opts...)
+	return o
+}
@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"context"
+	"github.com/cbwfree/micro-game/agent"
+	"github.com/cbwfree/micro-game/app"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/pb"
+	"github.com/golang/protobuf/proto"
+)
+
+// AddPush 注册推送消息的协议号 (推送的消息须先注册)
+func (r *Router) AddPush(cmd uint32, msgs ...proto.Message) error {
+	var errs RouteErrors
+	for _, msg := range msgs {
+		name := messageName(msg)
+		if exist, ok := r.pushes[name]; ok && exist != cmd {
+			errs = append(errs, errors.Exists("push message %s duplicate command %d with %d", name, cmd, exist))
+			continue
+		}
+		r.pushes[name] = cmd
+	}
+	return errs.Err()
+}
+
+// PushCmd 获取推送消息的协议号 (须通过 AddPush 注册)
+func (r *Router) PushCmd(msg proto.Message) (uint32, error) {
+	name := messageName(msg)
+	if cmd, ok := r.pushes[name]; ok {
+		return cmd, nil
+	}
+	return 0, errors.NotFound("not found push command for %s", name)
+}
+
+// Push 推送消息到客户端 (gmt 为客户端的网关Meta, 通过客户端所在的网关节点发送)
+func (r *Router) Push(ctx context.Context, gmt *agent.Meta, msg proto.Message) error {
+	cmd, err := r.PushCmd(msg)
+	if err != nil {
+		return err
+	}
+
+	srv, err := r.service()
+	if err != nil {
+		return err
+	}
+
+	s, err := r.Serializer(gmt)
+	if err != nil {
+		return err
+	}
+
+	data, err := s.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	in := &pb.Push{
+		ClientId: gmt.ClientId(),
+		Cmd:      cmd,
+		Data:     data,
+	}
+	return srv.CallNode(ctx, gmt.Name(), agent.PushMethod, in, new(pb.None), gmt.Id())
+}
+
+// PushRole 推送消息到角色 (通过角色Meta缓存查找所在网关)
+func (r *Router) PushRole(ctx context.Context, roleId int64, msg proto.Message) error {
+	gmt, err := agent.LoadRoleMeta(roleId)
+	if err != nil {
+		return err
+	}
+	return r.Push(ctx, gmt, msg)
+}
+
+// PushClient 推送消息到客户端连接 (通过客户端Meta缓存查找所在网关)
+func (r *Router) PushClient(ctx context.Context, clientId string, msg proto.Message) error {
+	gmt, err := agent.LoadClientMeta(clientId)
+	if err != nil {
+		return err
+	}
+	return r.Push(ctx, gmt, msg)
+}
+
+// UpdateAuth 同步认证信息到客户端所在的网关 (通过所属服务调用)
+func (r *Router) UpdateAuth(ctx context.Context, gmt *agent.Meta) error {
+	srv, err := r.service()
+	if err != nil {
+		return err
+	}
+	return agent.UpdateAuth(ctx, gmt, srv)
+}
+
+// 所属服务 (未设置时使用默认服务)
+func (r *Router) service() (*app.Service, error) {
+	if r.opts.Service != nil {
+		return r.opts.Service, nil
+	}
+	if s := app.Default(); s != nil {
+		return s, nil
+	}
+	return nil, errors.Unavailable("router service is not set")
+}
+
+// 消息全名
+func messageName(msg proto.Message) string {
+	return string(proto.MessageReflect(msg).Descriptor().FullName())
+}
@@ -1,21 +1,63 @@
 package protocol
 
 import (
+	"context"
 	"fmt"
 	"github.com/cbwfree/micro-game/agent"
 	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/golang/protobuf/proto"
 	"reflect"
 	"strconv"
 	"strings"
 )
 
+var (
+	typeOfContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+	typeOfMeta    = reflect.TypeOf((*agent.Meta)(nil))
+	typeOfMessage = reflect.TypeOf((*proto.Message)(nil)).Elem()
+	typeOfError   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// 协议处理函数 (ctx 携带RPC请求的截止时间及metadata, 并已附加网关Meta)
+type HandlerFunc func(ctx context.Context, gmt *agent.Meta, req, rsp proto.Message) error
+
+// RouteSkipper 协议处理对象实现此接口, 可以跳过不需要注册为协议的辅助方法
+type RouteSkipper interface {
+	SkipRoutes() []string
+}
+
+// RouteIdempotent 协议处理对象实现此接口, 标记需要按请求序号去重的方法 (如购买、消耗等)
+type RouteIdempotent interface {
+	IdempotentRoutes() []string
+}
+
+// RouteErrors 路由注册错误集合
+type RouteErrors []error
+
+func (es RouteErrors) Error() string {
+	var msgs = make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d route errors: %s", len(es), strings.Join(msgs, "; "))
+}
+
+// 返回错误集合 (无错误时返回nil)
+func (es RouteErrors) Err() error {
+	if len(es) == 0 {
+		return nil
+	}
+	return es
+}
+
 type Route struct {
 	cmd     uint32
 	name    string
-	hdlr    reflect.Value
-	method  reflect.Method
-	reqType reflect.Type
-	rspType reflect.Type
+	newReq  func() proto.Message
+	newRsp  func() proto.Message
+	handler HandlerFunc
+
+	idempotent bool // 同一请求序号只执行一次
 }
 
 func (h *Route) Cmd() uint32 {
@@ -26,75 +68,189 @@ func (h *Route) Name() string {
 	return h.name
 }
 
+// Idempotent 是否需要按请求序号去重
+func (h *Route) Idempotent() bool {
+	return h.idempotent
+}
+
+// SetIdempotent 设置是否需要按请求序号去重
+func (h *Route) SetIdempotent(idempotent bool) *Route {
+	h.idempotent = idempotent
+	return h
+}
+
+// NewReq 创建请求消息
+func (h *Route) NewReq() proto.Message {
+	return h.newReq()
+}
+
+// NewRsp 创建响应消息
+func (h *Route) NewRsp() proto.Message {
+	return h.newRsp()
+}
+
 func (h *Route) NewReqValue() reflect.Value {
-	return reflect.New(h.reqType)
+	return reflect.ValueOf(h.newReq())
 }
 
 func (h *Route) NewRspValue() reflect.Value {
-	return reflect.New(h.rspType)
+	return reflect.ValueOf(h.newRsp())
+}
+
+func (h *Route) Call(gmt *agent.Meta, req, rsp proto.Message) error {
+	return h.CallCtx(NewContext(gmt.Context(), gmt), gmt, req, rsp)
 }
 
-func (h *Route) Call(gmt *agent.Meta, req, rsp interface{}) error {
-	values := h.method.Func.Call([]reflect.Value{
-		h.hdlr,
-		reflect.ValueOf(gmt),
-		reflect.ValueOf(req),
-		reflect.ValueOf(rsp),
-	})
-	if err := values[0].Interface(); err != nil {
-		return err.(error)
+func (h *Route) CallCtx(ctx context.Context, gmt *agent.Meta, req, rsp proto.Message) error {
+	return h.handler(ctx, gmt, req, rsp)
+}
+
+// NewRoute 创建协议路由 (供 protoc-gen-route 生成的代码使用, 调用时无需反射)
+func NewRoute(cmd uint32, name string, newReq, newRsp func() proto.Message, handler HandlerFunc) *Route {
+	return &Route{
+		cmd:     cmd,
+		name:    name,
+		newReq:  newReq,
+		newRsp:  newRsp,
+		handler: handler,
 	}
-	return nil
 }
 
-func ParseRoutes(handler interface{}) []*Route {
+// ParseRoutes 通过反射解析协议处理对象 (方法名格式: Name_10001)
+// 	支持两种方法签名:
+// 		func(gmt *agent.Meta, c2s *C2S_10001, s2c *S2C_10001) error
+// 		func(ctx context.Context, c2s *C2S_10001, s2c *S2C_10001) error
+// 	处理函数发起的调用需归属于协议跨度时, 应使用 ctx 签名并通过 ctx 发起调用
+// 	未导出的方法不会被解析, 辅助方法可以通过实现 RouteSkipper 接口跳过
+// 	所有不合法的方法会合并为一个 RouteErrors 返回
+func ParseRoutes(handler interface{}) ([]*Route, error) {
 	var routes []*Route
+	var errs RouteErrors
 
 	typ := reflect.TypeOf(handler)
 	hdlr := reflect.ValueOf(handler)
+	if typ == nil {
+		return nil, errors.Invalid("route handler is nil")
+	}
 	name := reflect.Indirect(hdlr).Type().Name()
 
+	var skips = map[string]bool{"SkipRoutes": true, "IdempotentRoutes": true}
+	if s, ok := handler.(RouteSkipper); ok {
+		for _, m := range s.SkipRoutes() {
+			skips[m] = true
+		}
+	}
+
+	var idempotent = make(map[string]bool)
+	if s, ok := handler.(RouteIdempotent); ok {
+		for _, m := range s.IdempotentRoutes() {
+			idempotent[m] = true
+		}
+	}
+
+	var cmds = make(map[uint32]string)
 	for i := 0; i < typ.NumMethod(); i++ {
 		method := typ.Method(i)
+		if skips[method.Name] {
+			continue
+		}
+
+		mName := fmt.Sprintf("%s.%s", name, method.Name)
 
-		cmd, err := parseProtocolCmd(method.Name)
+		cmd, err := parseProtocolCmd(mName, method.Name)
 		if err != nil {
-			panic(err.Error())
+			errs = append(errs, err)
+			continue
 		}
 
-		mtype := method.Type
-		reqType := mtype.In(2)
-		rspType := mtype.In(3)
+		withCtx, err := checkMethodType(mName, method.Type)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
 
-		if reqType.Kind() == reflect.Ptr {
-			reqType = reqType.Elem()
+		if exist, ok := cmds[cmd]; ok {
+			errs = append(errs, errors.Exists("%s duplicate command %d with %s", mName, cmd, exist))
+			continue
 		}
-		if rspType.Kind() == reflect.Ptr {
-			rspType = rspType.Elem()
+		cmds[cmd] = mName
+
+		routes = append(routes, NewRoute(
+			cmd,
+			mName,
+			newMessageFunc(method.Type.In(2).Elem()),
+			newMessageFunc(method.Type.In(3).Elem()),
+			newMethodHandler(hdlr, method, withCtx),
+		).SetIdempotent(idempotent[method.Name]))
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
+
+// 检查方法签名, 返回是否为 context.Context 签名
+func checkMethodType(name string, mtype reflect.Type) (bool, error) {
+	if mtype.NumIn() != 4 {
+		return false, errors.Invalid("%s has wrong number of ins: %d", name, mtype.NumIn()-1)
+	}
+	withCtx := mtype.In(1) == typeOfContext
+	if !withCtx && mtype.In(1) != typeOfMeta {
+		return false, errors.Invalid("%s first argument type not *agent.Meta or context.Context: %s", name, mtype.In(1))
+	}
+	for i, arg := range []string{"request", "response"} {
+		in := mtype.In(i + 2)
+		if in.Kind() != reflect.Ptr || in.Elem().Kind() != reflect.Struct || !in.Implements(typeOfMessage) {
+			return false, errors.Invalid("%s %s type not a pointer to proto.Message: %s", name, arg, in)
 		}
+	}
+	if mtype.NumOut() != 1 {
+		return false, errors.Invalid("%s has wrong number of outs: %d", name, mtype.NumOut())
+	}
+	if mtype.Out(0) != typeOfError {
+		return false, errors.Invalid("%s returns %s not error", name, mtype.Out(0))
+	}
+	return withCtx, nil
+}
 
-		routes = append(routes, &Route{
-			cmd:     cmd,
-			name:    fmt.Sprintf("%s.%s", name, method.Name),
-			hdlr:    hdlr,
-			method:  method,
-			reqType: reqType,
-			rspType: rspType,
-		})
+// 通过反射创建消息
+func newMessageFunc(typ reflect.Type) func() proto.Message {
+	return func() proto.Message {
+		return reflect.New(typ).Interface().(proto.Message)
 	}
+}
 
-	return routes
+// 通过反射调用处理方法
+func newMethodHandler(hdlr reflect.Value, method reflect.Method, withCtx bool) HandlerFunc {
+	return func(ctx context.Context, gmt *agent.Meta, req, rsp proto.Message) error {
+		first := reflect.ValueOf(gmt)
+		if withCtx {
+			first = reflect.ValueOf(ctx)
+		}
+		values := method.Func.Call([]reflect.Value{
+			hdlr,
+			first,
+			reflect.ValueOf(req),
+			reflect.ValueOf(rsp),
+		})
+		if err := values[0].Interface(); err != nil {
+			return err.(error)
+		}
+		return nil
+	}
 }
 
-func parseProtocolCmd(name string) (uint32, error) {
-	names := strings.Split(name, "_")
+func parseProtocolCmd(name string, method string) (uint32, error) {
+	names := strings.Split(method, "_")
 	if len(names) != 2 {
-		return 0, errors.Invalid("%s.%s format error", name, name)
+		return 0, errors.Invalid("%s format error", name)
 	}
 
 	cmd, err := strconv.ParseUint(names[1], 10, 32)
-	if err != nil {
-		return 0, errors.Invalid("%s.%s invalid command: %s", name, name, names[1])
+	if err != nil || cmd == 0 {
+		return 0, errors.Invalid("%s invalid command: %s", name, names[1])
 	}
 
 	return uint32(cmd), nil
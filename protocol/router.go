@@ -1,14 +1,29 @@
 package protocol
 
 import (
+	"context"
 	"github.com/cbwfree/micro-game/agent"
 	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/cbwfree/micro-game/utils/metrics"
+	"github.com/cbwfree/micro-game/utils/trace"
 	"github.com/golang/protobuf/proto"
+	"time"
 )
 
 // 游戏协议路由
 type Router struct {
-	routes map[uint32]*Route
+	opts    *Options
+	routes  map[uint32]*Route
+	pushes  map[string]uint32        // 推送消息协议号
+	rules   map[string]*messageRules // 请求消息校验规则
+	breaker *breaker
+	actors  *Actors
+	dedup   *dedup
+}
+
+func (r *Router) Options() *Options {
+	return r.opts
 }
 
 // 注册
@@ -20,37 +35,214 @@ func (r *Router) Routes() map[uint32]*Route {
 	return routes
 }
 
-// 注册
-func (r *Router) AddRoute(handles ...interface{}) {
+// 注册 (任意一个处理对象不合法时, 全部不会注册)
+func (r *Router) AddRoute(handles ...interface{}) error {
+	var routes []*Route
+	var errs RouteErrors
+
 	for _, handler := range handles {
-		for _, h := range ParseRoutes(handler) {
-			r.routes[h.cmd] = h
+		rs, err := ParseRoutes(handler)
+		if err != nil {
+			if es, ok := err.(RouteErrors); ok {
+				errs = append(errs, es...)
+			} else {
+				errs = append(errs, err)
+			}
+			continue
 		}
+		routes = append(routes, rs...)
+	}
+
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	return r.Handle(routes...)
+}
+
+// Handle 注册路由 (protoc-gen-route 生成的静态路由表)
+// 	协议号重复时返回错误, 全部不会注册
+func (r *Router) Handle(routes ...*Route) error {
+	var errs RouteErrors
+	var cmds = make(map[uint32]string, len(routes))
+
+	for _, route := range routes {
+		if route == nil || route.handler == nil || route.newReq == nil || route.newRsp == nil {
+			errs = append(errs, errors.Invalid("invalid route: %v", route))
+			continue
+		}
+		if exist, ok := r.routes[route.cmd]; ok {
+			errs = append(errs, errors.Exists("%s duplicate command %d with %s", route.name, route.cmd, exist.name))
+			continue
+		}
+		if exist, ok := cmds[route.cmd]; ok {
+			errs = append(errs, errors.Exists("%s duplicate command %d with %s", route.name, route.cmd, exist))
+			continue
+		}
+		cmds[route.cmd] = route.name
+	}
+
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	for _, route := range routes {
+		r.routes[route.cmd] = route
 	}
+
+	return nil
 }
 
 // 调用
 func (r *Router) Call(gmt *agent.Meta, cmd uint32, req []byte) (rsp []byte, err error) {
+	return r.CallCtx(gmt.Context(), gmt, cmd, req)
+}
+
+// CallCtx 调用 (ctx 一般为 RPC 请求的上下文, 携带截止时间及metadata)
+func (r *Router) CallCtx(ctx context.Context, gmt *agent.Meta, cmd uint32, req []byte) (rsp []byte, err error) {
 	route, ok := r.routes[cmd]
 	if !ok {
 		return nil, errors.NotFound("not found protocol %d", cmd)
 	}
 
-	c2s := route.NewReqValue().Interface()
-	if err := proto.Unmarshal(req, c2s.(proto.Message)); err != nil {
+	if r.dedup != nil && route.idempotent {
+		return r.dedup.Do(gmt, cmd, func() ([]byte, error) {
+			return r.call(ctx, gmt, route, req)
+		})
+	}
+
+	return r.call(ctx, gmt, route, req)
+}
+
+// 解码请求并执行协议处理
+func (r *Router) call(ctx context.Context, gmt *agent.Meta, route *Route, req []byte) ([]byte, error) {
+	s, err := r.Serializer(gmt)
+	if err != nil {
 		return nil, err
 	}
 
-	s2c := route.NewRspValue().Interface()
-	if err := route.Call(gmt, c2s, s2c); err != nil {
+	c2s := route.NewReq()
+	if err := s.Unmarshal(req, c2s); err != nil {
 		return nil, err
 	}
 
-	return proto.Marshal(s2c.(proto.Message))
+	s2c := route.NewRsp()
+	if err := r.invoke(ctx, gmt, route, c2s, s2c); err != nil {
+		return nil, err
+	}
+
+	return s.Marshal(s2c)
+}
+
+// Serializer 获取客户端使用的序列化方式 (客户端未指定时使用路由默认设置)
+func (r *Router) Serializer(gmt *agent.Meta) (Serializer, error) {
+	name := gmt.Serializer()
+	if name == "" || name == r.opts.Serializer.Name() {
+		return r.opts.Serializer, nil
+	}
+	if s, ok := GetSerializer(name); ok {
+		return s, nil
+	}
+	return nil, errors.Invalid("unsupported serializer: %s", name)
+}
+
+// Invoke 直接调用协议处理 (无需编解码, 供生成的客户端使用)
+func (r *Router) Invoke(gmt *agent.Meta, cmd uint32, req, rsp proto.Message) error {
+	return r.InvokeCtx(gmt.Context(), gmt, cmd, req, rsp)
+}
+
+// InvokeCtx 直接调用协议处理
+func (r *Router) InvokeCtx(ctx context.Context, gmt *agent.Meta, cmd uint32, req, rsp proto.Message) error {
+	route, ok := r.routes[cmd]
+	if !ok {
+		return errors.NotFound("not found protocol %d", cmd)
+	}
+	return r.invoke(ctx, gmt, route, req, rsp)
+}
+
+// 执行协议处理 (记录协议跨度及监控指标)
+func (r *Router) invoke(ctx context.Context, gmt *agent.Meta, route *Route, req, rsp proto.Message) (err error) {
+	now := time.Now()
+	ctx, span := trace.Start(ctx, route.name, trace.KindInternal)
+	span.SetAttr("cmd", route.cmd)
+	span.SetAttr("role", gmt.RoleId())
+	defer func() {
+		span.Finish(err)
+		metrics.ObserveRoute(route.cmd, time.Since(now), err)
+	}()
+
+	// 协议跨度仅通过上下文传递 (不修改网关Meta), 处理函数通过 ctx 发起的调用归属于协议跨度
+	return r.dispatch(ctx, gmt, route, req, rsp)
+}
+
+// 校验请求并分派到协议处理
+func (r *Router) dispatch(ctx context.Context, gmt *agent.Meta, route *Route, req, rsp proto.Message) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Timeout("protocol %d: %s", route.cmd, err)
+	}
+	if err := r.Validate(req); err != nil {
+		return err
+	}
+	if r.breaker != nil && !r.breaker.Allow(route.cmd) {
+		return errors.Unavailable("protocol %d is unavailable", route.cmd)
+	}
+	if r.actors != nil && gmt.RoleId() != 0 {
+		// 邮箱任务使用请求及响应的副本, 等待超时后仍在执行的任务不会写入调用方的消息
+		in, out := proto.Clone(req), proto.Clone(rsp)
+		if err := r.actors.Do(ctx, gmt.RoleId(), func(ctx context.Context) error {
+			return r.safeCall(ctx, gmt, route, in, out)
+		}); err != nil {
+			return err
+		}
+		rsp.Reset()
+		proto.Merge(rsp, out)
+		return nil
+	}
+	return r.safeCall(ctx, gmt, route, req, rsp)
+}
+
+// Actors 获取角色邮箱 (未启用时返回nil), 可用于投递离线通知及定时任务
+func (r *Router) Actors() *Actors {
+	return r.actors
 }
 
-func NewRouter() *Router {
-	return &Router{
+// 执行协议处理, 捕获处理函数中的panic, 避免网关连接或游戏服务崩溃
+func (r *Router) safeCall(ctx context.Context, gmt *agent.Meta, route *Route, req, rsp proto.Message) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.Error("[Router] protocol %d (%s) role %d panic: %+v", route.cmd, route.name, gmt.RoleId(), errors.Recover(v))
+			if r.breaker != nil && r.breaker.Panic(route.cmd) {
+				log.Warn("[Router] protocol %d (%s) circuit breaker open", route.cmd, route.name)
+			}
+			err = errors.Server("protocol %d server error", route.cmd)
+		}
+	}()
+	return route.CallCtx(NewContext(ctx, gmt), gmt, req, rsp)
+}
+
+// Breakers 获取熔断中的协议及熔断截止时间
+func (r *Router) Breakers() map[uint32]time.Time {
+	if r.breaker == nil {
+		return nil
+	}
+	return r.breaker.State()
+}
+
+func NewRouter(opts ...Option) *Router {
+	r := &Router{
+		opts:   newOptions(opts...),
 		routes: make(map[uint32]*Route),
+		pushes: make(map[string]uint32),
+		rules:  make(map[string]*messageRules),
+	}
+	if r.opts.Breaker != nil {
+		r.breaker = newBreaker(r.opts.Breaker)
+	}
+	if r.opts.Actor != nil {
+		r.actors = NewActors(r.opts.Actor)
+	}
+	if r.opts.Dedup != nil {
+		r.dedup = newDedup(r.opts.Dedup)
 	}
+	return r
 }
@@ -4,8 +4,17 @@ import (
 	"context"
 	"fmt"
 	"github.com/cbwfree/micro-game/agent"
+	"github.com/cbwfree/micro-game/meta"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/pb"
+	"github.com/cbwfree/micro-game/utils/trace"
 	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/types/known/structpb"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type testHandle struct{}
@@ -15,6 +24,71 @@ func (t *testHandle) Test_10001(ctx context.Context, c2s proto.Message, s2c prot
 	return nil
 }
 
+type testMetaHandle struct{}
+
+func (t *testMetaHandle) Test_10001(gmt *agent.Meta, c2s *pb.Cancel, s2c *pb.None) error {
+	fmt.Printf("call Test_10001, c2s: %+v, s2c: %+v\n", c2s, s2c)
+	return nil
+}
+
+func newTestMeta() *agent.Meta {
+	return &agent.Meta{Meta: meta.ToMeta(map[string]string{
+		agent.MetaClientId: "test",
+	})}
+}
+
+type testInvalidHandle struct{}
+
+func (t *testInvalidHandle) NoCmd(gmt *agent.Meta, c2s *pb.Cancel, s2c *pb.None) error {
+	return nil
+}
+
+func (t *testInvalidHandle) BadMeta_10002(gmt *meta.Meta, c2s *pb.Cancel, s2c *pb.None) error {
+	return nil
+}
+
+func (t *testInvalidHandle) BadReq_10003(gmt *agent.Meta, c2s string, s2c *pb.None) error {
+	return nil
+}
+
+func (t *testInvalidHandle) BadOut_10004(gmt *agent.Meta, c2s *pb.Cancel, s2c *pb.None) {
+}
+
+type testSkipHandle struct{}
+
+func (t *testSkipHandle) SkipRoutes() []string {
+	return []string{"Helper"}
+}
+
+func (t *testSkipHandle) Helper() {}
+
+func (t *testSkipHandle) Dup_10001(gmt *agent.Meta, c2s *pb.Cancel, s2c *pb.None) error {
+	return nil
+}
+
+func TestRouter_AddRoute(t *testing.T) {
+	r := NewRouter()
+
+	err := r.AddRoute(new(testInvalidHandle))
+	es, ok := err.(RouteErrors)
+	if !ok || len(es) != 4 {
+		t.Fatalf("expected 4 route errors, got: %v", err)
+	}
+
+	if err := r.AddRoute(new(testMetaHandle)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 辅助方法被跳过, 协议号与已注册路由冲突
+	err = r.AddRoute(new(testSkipHandle))
+	if es, ok := err.(RouteErrors); !ok || len(es) != 1 {
+		t.Fatalf("expected 1 duplicate error, got: %v", err)
+	}
+	if len(r.Routes()) != 1 {
+		t.Fatalf("unexpected routes: %+v", r.Routes())
+	}
+}
+
 func TestRouter_Call(t *testing.T) {
 	r := NewRouter()
 	r.AddRoute(new(testHandle))
@@ -32,3 +106,523 @@ func TestRouter_Handler(t *testing.T) {
 
 	fmt.Printf("Routes: %+v\n", r.Routes())
 }
+
+func TestRouter_Handle(t *testing.T) {
+	r := NewRouter()
+	r.Handle(NewRoute(10002, "Test.Echo",
+		func() proto.Message { return new(pb.Cancel) },
+		func() proto.Message { return new(pb.Cancel) },
+		func(ctx context.Context, gmt *agent.Meta, req, rsp proto.Message) error {
+			rsp.(*pb.Cancel).Name = req.(*pb.Cancel).Name
+			return nil
+		},
+	))
+
+	req, _ := proto.Marshal(&pb.Cancel{Name: "echo"})
+	res, err := r.Call(newTestMeta(), 10002, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2c := new(pb.Cancel)
+	if err := proto.Unmarshal(res, s2c); err != nil {
+		t.Fatal(err)
+	}
+	if s2c.Name != "echo" {
+		t.Fatalf("unexpected response: %+v", s2c)
+	}
+}
+
+func TestRouter_Invoke(t *testing.T) {
+	r := NewRouter()
+	if err := r.AddRoute(new(testMetaHandle)); err != nil {
+		t.Fatal(err)
+	}
+	r.Handle(NewRoute(10002, "Test.Echo",
+		func() proto.Message { return new(pb.Cancel) },
+		func() proto.Message { return new(pb.Cancel) },
+		func(ctx context.Context, gmt *agent.Meta, req, rsp proto.Message) error {
+			rsp.(*pb.Cancel).Name = req.(*pb.Cancel).Name
+			return nil
+		},
+	))
+
+	out := new(pb.Cancel)
+	if err := r.Invoke(newTestMeta(), 10002, &pb.Cancel{Name: "invoke"}, out); err != nil || out.Name != "invoke" {
+		t.Fatalf("invoke error: %v, %+v", err, out)
+	}
+
+	if err := r.Invoke(newTestMeta(), 10001, new(pb.Cancel), new(pb.None)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Invoke(newTestMeta(), 10003, new(pb.Cancel), new(pb.None)); err == nil || !errors.IsCode(err, errors.CodeNotFound) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+}
+
+type testCtxHandle struct{}
+
+func (t *testCtxHandle) Echo_10005(ctx context.Context, c2s *pb.Cancel, s2c *pb.Cancel) error {
+	gmt, ok := FromContext(ctx)
+	if !ok {
+		return fmt.Errorf("no meta in context")
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return fmt.Errorf("no deadline in context")
+	}
+	s2c.Name = c2s.Name
+	s2c.NodeId = gmt.ClientId()
+	return nil
+}
+
+func TestRouter_CallCtx(t *testing.T) {
+	r := NewRouter()
+	if err := r.AddRoute(new(testCtxHandle)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	out := new(pb.Cancel)
+	if err := r.InvokeCtx(ctx, newTestMeta(), 10005, &pb.Cancel{Name: "ctx"}, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "ctx" || out.NodeId != "test" {
+		t.Fatalf("unexpected response: %+v", out)
+	}
+
+	// 超时的请求不再执行
+	expired, cancel2 := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel2()
+	if _, err := r.CallCtx(expired, newTestMeta(), 10005, nil); err == nil {
+		t.Fatal("expected deadline error")
+	}
+}
+
+type testPanicHandle struct{}
+
+func (t *testPanicHandle) Panic_10006(gmt *agent.Meta, c2s *pb.Cancel, s2c *pb.None) error {
+	var m map[string]int
+	m[c2s.Name]++
+	return nil
+}
+
+func TestRouter_Panic(t *testing.T) {
+	r := NewRouter(WithBreaker(2, time.Minute, 50*time.Millisecond))
+	if err := r.AddRoute(new(testPanicHandle)); err != nil {
+		t.Fatal(err)
+	}
+
+	gmt := newTestMeta()
+	for i := 0; i < 2; i++ {
+		if err := r.Invoke(gmt, 10006, new(pb.Cancel), new(pb.None)); !errors.IsCode(err, errors.CodeServerError) {
+			t.Fatalf("expected server error, got: %v", err)
+		}
+	}
+
+	// 熔断中的协议直接返回不可用
+	if err := r.Invoke(gmt, 10006, new(pb.Cancel), new(pb.None)); !errors.IsCode(err, errors.CodeServiceUnavailable) {
+		t.Fatalf("expected unavailable error, got: %v", err)
+	}
+	if _, ok := r.Breakers()[10006]; !ok {
+		t.Fatal("expected breaker open")
+	}
+
+	// 熔断结束后恢复执行
+	time.Sleep(60 * time.Millisecond)
+	if err := r.Invoke(gmt, 10006, new(pb.Cancel), new(pb.None)); !errors.IsCode(err, errors.CodeServerError) {
+		t.Fatalf("expected server error, got: %v", err)
+	}
+}
+
+func TestRouter_Schema(t *testing.T) {
+	r := NewRouter()
+	if err := r.AddRoute(new(testMetaHandle), new(testCtxHandle)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := r.Schema()
+	if len(s.Routes) != 2 || s.Routes[0].Cmd != 10001 || s.Routes[1].Cmd != 10005 {
+		t.Fatalf("unexpected routes: %+v", s.Routes)
+	}
+	if s.Routes[0].Request != "pb.Cancel" || s.Routes[0].Response != "pb.None" {
+		t.Fatalf("unexpected route schema: %+v", s.Routes[0])
+	}
+	if m, ok := s.Messages["pb.Cancel"]; !ok || len(m.Fields) == 0 || len(m.Descriptor) == 0 {
+		t.Fatalf("unexpected message schema: %+v", m)
+	}
+
+	if _, err := r.SchemaJSON(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRouter_Serializer(t *testing.T) {
+	r := NewRouter()
+	if err := r.AddRoute(new(testCtxHandle)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{SerializerProto, SerializerJson, SerializerMsgpack} {
+		s, _ := GetSerializer(name)
+		req, err := s.Marshal(&pb.Cancel{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		gmt := newTestMeta()
+		gmt.Set(agent.MetaSerializer, name)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		b, err := r.CallCtx(ctx, gmt, 10005, req)
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out := new(pb.Cancel)
+		if err := s.Unmarshal(b, out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Name != name || out.NodeId != "test" {
+			t.Fatalf("%s unexpected response: %+v", name, out)
+		}
+	}
+
+	gmt := newTestMeta()
+	gmt.Set(agent.MetaSerializer, "xml")
+	if _, err := r.Call(gmt, 10005, nil); !errors.IsCode(err, errors.CodeInvalid) {
+		t.Fatalf("expected invalid serializer error, got: %v", err)
+	}
+}
+
+func TestMsgpackSerializer(t *testing.T) {
+	s, _ := GetSerializer(SerializerMsgpack)
+
+	dl := &pb.DeadLetter{Topic: "test", Body: []byte{1, 2}, Attempts: 3, Time: 1 << 40}
+	b, err := s.Marshal(dl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 客户端按原生类型解析 (数值为浮点数或字符串时解析失败)
+	var v struct {
+		Topic    string
+		Body     []byte
+		Attempts int32
+		Time     int64
+	}
+	if err := msgpack.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Topic != dl.Topic || v.Attempts != dl.Attempts || v.Time != dl.Time || len(v.Body) != 2 {
+		t.Fatalf("unexpected value: %+v", v)
+	}
+
+	out := new(pb.DeadLetter)
+	if err := s.Unmarshal(b, out); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(dl, out) {
+		t.Fatalf("unexpected message: %+v", out)
+	}
+
+	// 嵌套消息、列表、map、浮点数及 oneof
+	st := &structpb.Struct{Fields: map[string]*structpb.Value{
+		"name":  {Kind: &structpb.Value_StringValue{StringValue: "test"}},
+		"score": {Kind: &structpb.Value_NumberValue{NumberValue: 1.5}},
+		"null":  {Kind: &structpb.Value_NullValue{}},
+		"list": {Kind: &structpb.Value_ListValue{ListValue: &structpb.ListValue{Values: []*structpb.Value{
+			{Kind: &structpb.Value_BoolValue{BoolValue: true}},
+			{Kind: &structpb.Value_StringValue{StringValue: "a"}},
+		}}}},
+		"sub": {Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{Fields: map[string]*structpb.Value{
+			"x": {Kind: &structpb.Value_NumberValue{NumberValue: 2}},
+		}}}},
+	}}
+	if b, err = s.Marshal(st); err != nil {
+		t.Fatal(err)
+	}
+	out2 := new(structpb.Struct)
+	if err := s.Unmarshal(b, out2); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(st, out2) {
+		t.Fatalf("unexpected struct: %v", out2)
+	}
+
+	// JavaScript 客户端的整数可能编码为浮点数, int64 可能为字符串
+	b, _ = msgpack.Marshal(map[string]interface{}{"Attempts": 2.0, "Time": "1099511627776", "Unknown": 1})
+	out = new(pb.DeadLetter)
+	if err := s.Unmarshal(b, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Attempts != 2 || out.Time != 1<<40 {
+		t.Fatalf("unexpected message: %+v", out)
+	}
+
+	b, _ = msgpack.Marshal(map[string]interface{}{"Attempts": 1.5})
+	if err := s.Unmarshal(b, new(pb.DeadLetter)); err == nil {
+		t.Fatal("expected invalid integer error")
+	}
+}
+
+func TestRouter_PushCmd(t *testing.T) {
+	r := NewRouter()
+	if err := r.AddRoute(new(testCtxHandle)); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未注册的消息 (路由响应消息也须注册)
+	for _, msg := range []proto.Message{new(pb.Cancel), new(pb.None)} {
+		if _, err := r.PushCmd(msg); !errors.IsCode(err, errors.CodeNotFound) {
+			t.Fatalf("expected not found error, got: %v", err)
+		}
+	}
+
+	// 注册推送消息
+	if err := r.AddPush(20001, new(pb.None)); err != nil {
+		t.Fatal(err)
+	}
+	if cmd, err := r.PushCmd(new(pb.None)); err != nil || cmd != 20001 {
+		t.Fatalf("unexpected push command: %d, %v", cmd, err)
+	}
+	if err := r.AddPush(20002, new(pb.None)); err == nil {
+		t.Fatal("expected duplicate push error")
+	}
+
+	// 未连接Redis时无法查找客户端所在网关
+	if err := r.PushRole(context.Background(), 1, new(pb.None)); !errors.IsCode(err, errors.CodeServiceUnavailable) {
+		t.Fatalf("expected unavailable error, got: %v", err)
+	}
+}
+
+func TestRouter_Actor(t *testing.T) {
+	r := NewRouter(WithActor(128, 50*time.Millisecond))
+
+	var running, maxRunning, count int32
+	err := r.Handle(NewRoute(10007, "Test.Serial",
+		func() proto.Message { return new(pb.None) },
+		func() proto.Message { return new(pb.None) },
+		func(ctx context.Context, gmt *agent.Meta, req, rsp proto.Message) error {
+			n := atomic.AddInt32(&running, 1)
+			if n > atomic.LoadInt32(&maxRunning) {
+				atomic.StoreInt32(&maxRunning, n)
+			}
+			time.Sleep(time.Millisecond)
+			count++
+			atomic.AddInt32(&running, -1)
+			return nil
+		},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gmt := newTestMeta()
+	gmt.Set(agent.MetaRoleId, 1001)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := r.InvokeCtx(ctx, gmt, 10007, new(pb.None), new(pb.None)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count != 20 || maxRunning != 1 {
+		t.Fatalf("expected serial execution, count: %d, max running: %d", count, maxRunning)
+	}
+	if r.Actors().Len() != 1 {
+		t.Fatalf("expected 1 mailbox, got: %d", r.Actors().Len())
+	}
+
+	// 空闲邮箱回收
+	time.Sleep(100 * time.Millisecond)
+	if r.Actors().Len() != 0 {
+		t.Fatalf("expected mailbox evicted, got: %d", r.Actors().Len())
+	}
+
+	// 邮箱内重入调用
+	err = r.Actors().Do(context.Background(), 1001, func(ctx context.Context) error {
+		return r.InvokeCtx(ctx, gmt, 10007, new(pb.None), new(pb.None))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRouter_ActorTimeout(t *testing.T) {
+	r := NewRouter(WithActor(8, time.Minute))
+
+	release := make(chan struct{})
+	finished := make(chan struct{})
+	err := r.Handle(NewRoute(10009, "Test.Slow",
+		func() proto.Message { return new(pb.Cancel) },
+		func() proto.Message { return new(pb.Cancel) },
+		func(ctx context.Context, gmt *agent.Meta, req, rsp proto.Message) error {
+			defer close(finished)
+			<-release
+			rsp.(*pb.Cancel).Name = "late"
+			return errors.Server("late error")
+		},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gmt := newTestMeta()
+	gmt.Set(agent.MetaRoleId, 1001)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	rsp := new(pb.Cancel)
+	if err := r.InvokeCtx(ctx, gmt, 10009, new(pb.Cancel), rsp); !errors.IsCode(err, errors.CodeTimeout) {
+		t.Fatalf("expected timeout, got: %v", err)
+	}
+
+	// 超时后任务继续执行完成, 结果不会写入调用方的响应
+	close(release)
+	<-finished
+	if rsp.Name != "" {
+		t.Fatalf("response written after timeout: %+v", rsp)
+	}
+}
+
+type testDedupHandle struct {
+	count int
+}
+
+func (t *testDedupHandle) IdempotentRoutes() []string {
+	return []string{"Buy_10008"}
+}
+
+func (t *testDedupHandle) Buy_10008(gmt *agent.Meta, c2s *pb.Cancel, s2c *pb.Cancel) error {
+	t.count++
+	s2c.Name = fmt.Sprintf("%s-%d", c2s.Name, t.count)
+	return nil
+}
+
+func TestRouter_Dedup(t *testing.T) {
+	h := new(testDedupHandle)
+	r := NewRouter(WithDedup(50 * time.Millisecond))
+	if err := r.AddRoute(h); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Routes()[10008].Idempotent() {
+		t.Fatal("expected idempotent route")
+	}
+
+	req, _ := proto.Marshal(&pb.Cancel{Name: "buy"})
+	gmt := newTestMeta()
+	gmt.Set(agent.MetaSerial, 1)
+
+	first, err := r.Call(gmt, 10008, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 重复的请求序号返回缓存的响应
+	replay, err := r.Call(gmt, 10008, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.count != 1 || string(first) != string(replay) {
+		t.Fatalf("expected cached response, count: %d", h.count)
+	}
+
+	// 新的请求序号重新执行
+	gmt.Set(agent.MetaSerial, 2)
+	if _, err := r.Call(gmt, 10008, req); err != nil || h.count != 2 {
+		t.Fatalf("expected new execution, count: %d, err: %v", h.count, err)
+	}
+
+	// 超出时间窗口后重新执行
+	time.Sleep(60 * time.Millisecond)
+	gmt.Set(agent.MetaSerial, 1)
+	if _, err := r.Call(gmt, 10008, req); err != nil || h.count != 3 {
+		t.Fatalf("expected execution after window, count: %d, err: %v", h.count, err)
+	}
+}
+
+func TestRouter_Validate(t *testing.T) {
+	r := NewRouter()
+	if err := r.AddRoute(new(testMetaHandle)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetRules(new(pb.Cancel), Rules{"Unknown": NewRule()}); err == nil {
+		t.Fatal("expected unknown field error")
+	}
+	err := r.SetRules(new(pb.Cancel), Rules{
+		"Name":   NewRule().Required().Len(2, 8).Format("chsAlphaNum"),
+		"NodeId": NewRule().Format("alphaDash"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cases = []struct {
+		req   *pb.Cancel
+		valid bool
+	}{
+		{&pb.Cancel{Name: "游戏01"}, true},
+		{&pb.Cancel{Name: "游戏01", NodeId: "node-1"}, true},
+		{&pb.Cancel{}, false},
+		{&pb.Cancel{Name: "a"}, false},
+		{&pb.Cancel{Name: "game_01"}, false},
+		{&pb.Cancel{Name: "game", NodeId: "node 1"}, false},
+	}
+	for _, c := range cases {
+		err := r.Invoke(newTestMeta(), 10001, c.req, new(pb.None))
+		if c.valid && err != nil {
+			t.Fatalf("%+v: unexpected error: %s", c.req, err)
+		}
+		if !c.valid && errors.Parse(err).Code != errors.CodeInvalid {
+			t.Fatalf("%+v: expected invalid error, got: %v", c.req, err)
+		}
+	}
+
+	// 字段错误按字段编号排序
+	for i := 0; i < 10; i++ {
+		err := r.Invoke(newTestMeta(), 10001, &pb.Cancel{NodeId: "node 1"}, new(pb.None))
+		errs := ParseFieldErrors(err)
+		if len(errs) != 2 || errs[0].Field != "Name" || errs[1].Field != "NodeId" || errs[1].Message != "format must be alphaDash" {
+			t.Fatalf("unexpected field errors: %v", err)
+		}
+	}
+}
+
+func TestRouter_Trace(t *testing.T) {
+	mem := trace.NewMemoryExporter()
+	trace.SetExporter(mem)
+	defer trace.SetExporter(nil)
+
+	r := NewRouter()
+	if err := r.AddRoute(new(testMetaHandle)); err != nil {
+		t.Fatal(err)
+	}
+
+	gmt := newTestMeta()
+	ctx, span := trace.Start(gmt.RequestContext(1), "Forward.Protocol", trace.KindServer)
+	if err := r.InvokeCtx(ctx, gmt, 10001, new(pb.Cancel), new(pb.None)); err != nil {
+		t.Fatal(err)
+	}
+	span.Finish(nil)
+
+	spans := mem.Trace(span.TraceId)
+	if len(spans) != 2 || spans[0].ParentId != span.SpanId || spans[0].Attrs["cmd"] != "10001" {
+		t.Fatalf("unexpected spans: %d", len(spans))
+	}
+	if _, ok := gmt.Metadata()[trace.MetaTraceId]; ok {
+		t.Fatal("invoke should not modify the gate meta")
+	}
+	if _, ok := gmt.Metadata()[trace.MetaSpanId]; ok {
+		t.Fatal("invoke should not modify the gate meta")
+	}
+}
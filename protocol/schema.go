@@ -0,0 +1,149 @@
+package protocol
+
+import (
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"sort"
+)
+
+// 协议文档
+type Schema struct {
+	Routes   []*RouteSchema            `json:"routes"`   // 协议列表 (按协议号排序)
+	Messages map[string]*MessageSchema `json:"messages"` // 协议引用的所有消息 (含嵌套引用)
+	Enums    map[string]*EnumSchema    `json:"enums"`    // 协议引用的所有枚举
+}
+
+// 协议路由文档
+type RouteSchema struct {
+	Cmd      uint32 `json:"cmd"`      // 协议号
+	Name     string `json:"name"`     // 处理方法名称
+	Request  string `json:"request"`  // 请求消息全名
+	Response string `json:"response"` // 响应消息全名
+}
+
+// 消息文档
+type MessageSchema struct {
+	Name       string          `json:"name"`       // 消息全名
+	File       string          `json:"file"`       // 定义文件
+	Fields     []*FieldSchema  `json:"fields"`     // 字段列表
+	Descriptor json.RawMessage `json:"descriptor"` // DescriptorProto (protojson格式)
+}
+
+// 字段文档
+type FieldSchema struct {
+	Name     string `json:"name"`                // 字段名称
+	JsonName string `json:"json_name"`           // JSON名称
+	Number   int32  `json:"number"`              // 字段编号
+	Kind     string `json:"kind"`                // 字段类型
+	TypeName string `json:"type_name,omitempty"` // 消息或枚举全名 (map为值类型)
+	Repeated bool   `json:"repeated,omitempty"`  // 是否为数组
+	Map      bool   `json:"map,omitempty"`       // 是否为map
+}
+
+// 枚举文档
+type EnumSchema struct {
+	Name   string           `json:"name"`   // 枚举全名
+	File   string           `json:"file"`   // 定义文件
+	Values map[string]int32 `json:"values"` // 枚举值
+}
+
+// Schema 导出路由表文档
+func (r *Router) Schema() *Schema {
+	s := &Schema{
+		Routes:   make([]*RouteSchema, 0, len(r.routes)),
+		Messages: make(map[string]*MessageSchema),
+		Enums:    make(map[string]*EnumSchema),
+	}
+
+	for _, route := range r.routes {
+		req := proto.MessageReflect(route.NewReq()).Descriptor()
+		rsp := proto.MessageReflect(route.NewRsp()).Descriptor()
+		s.Routes = append(s.Routes, &RouteSchema{
+			Cmd:      route.cmd,
+			Name:     route.name,
+			Request:  string(req.FullName()),
+			Response: string(rsp.FullName()),
+		})
+		s.addMessage(req)
+		s.addMessage(rsp)
+	}
+
+	sort.Slice(s.Routes, func(i, j int) bool {
+		return s.Routes[i].Cmd < s.Routes[j].Cmd
+	})
+
+	return s
+}
+
+// SchemaJSON 导出JSON格式的路由表文档
+func (r *Router) SchemaJSON() ([]byte, error) {
+	return json.Marshal(r.Schema())
+}
+
+// 添加消息文档 (递归添加字段引用的消息及枚举)
+func (s *Schema) addMessage(md protoreflect.MessageDescriptor) {
+	name := string(md.FullName())
+	if _, ok := s.Messages[name]; ok {
+		return
+	}
+
+	ms := &MessageSchema{
+		Name: name,
+		File: md.ParentFile().Path(),
+	}
+	s.Messages[name] = ms
+
+	if b, err := protojson.Marshal(protodesc.ToDescriptorProto(md)); err == nil {
+		ms.Descriptor = b
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		fs := &FieldSchema{
+			Name:     string(fd.Name()),
+			JsonName: fd.JSONName(),
+			Number:   int32(fd.Number()),
+			Kind:     fd.Kind().String(),
+			Repeated: fd.IsList(),
+			Map:      fd.IsMap(),
+		}
+		ms.Fields = append(ms.Fields, fs)
+
+		if fd.IsMap() {
+			fs.Kind = fd.MapValue().Kind().String()
+			fd = fd.MapValue()
+		}
+		switch {
+		case fd.Message() != nil:
+			fs.TypeName = string(fd.Message().FullName())
+			s.addMessage(fd.Message())
+		case fd.Enum() != nil:
+			fs.TypeName = string(fd.Enum().FullName())
+			s.addEnum(fd.Enum())
+		}
+	}
+}
+
+// 添加枚举文档
+func (s *Schema) addEnum(ed protoreflect.EnumDescriptor) {
+	name := string(ed.FullName())
+	if _, ok := s.Enums[name]; ok {
+		return
+	}
+
+	es := &EnumSchema{
+		Name:   name,
+		File:   ed.ParentFile().Path(),
+		Values: make(map[string]int32),
+	}
+	values := ed.Values()
+	for i := 0; i < values.Len(); i++ {
+		v := values.Get(i)
+		es.Values[string(v.Name())] = int32(v.Number())
+	}
+	s.Enums[name] = es
+}
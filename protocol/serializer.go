@@ -0,0 +1,109 @@
+package protocol
+
+import (
+	"bytes"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/encoding/protojson"
+	"sync"
+)
+
+const (
+	SerializerProto   = "proto"   // protobuf (默认)
+	SerializerJson    = "json"    // protojson
+	SerializerMsgpack = "msgpack" // MessagePack
+)
+
+var serializers = struct {
+	sync.RWMutex
+	m map[string]Serializer
+}{
+	m: map[string]Serializer{
+		SerializerProto:   new(ProtoSerializer),
+		SerializerJson:    new(JsonSerializer),
+		SerializerMsgpack: new(MsgpackSerializer),
+	},
+}
+
+// 协议数据序列化
+type Serializer interface {
+	Name() string
+	Marshal(m proto.Message) ([]byte, error)
+	Unmarshal(b []byte, m proto.Message) error
+}
+
+// RegisterSerializer 注册序列化方式 (同名时覆盖)
+func RegisterSerializer(s Serializer) {
+	serializers.Lock()
+	defer serializers.Unlock()
+
+	serializers.m[s.Name()] = s
+}
+
+// GetSerializer 获取序列化方式
+func GetSerializer(name string) (Serializer, bool) {
+	serializers.RLock()
+	defer serializers.RUnlock()
+
+	s, ok := serializers.m[name]
+	return s, ok
+}
+
+// protobuf 序列化
+type ProtoSerializer struct{}
+
+func (s *ProtoSerializer) Name() string {
+	return SerializerProto
+}
+
+func (s *ProtoSerializer) Marshal(m proto.Message) ([]byte, error) {
+	return proto.Marshal(m)
+}
+
+func (s *ProtoSerializer) Unmarshal(b []byte, m proto.Message) error {
+	return proto.Unmarshal(b, m)
+}
+
+// JSON 序列化 (protojson, 字段名使用proto定义的名称)
+type JsonSerializer struct{}
+
+func (s *JsonSerializer) Name() string {
+	return SerializerJson
+}
+
+func (s *JsonSerializer) Marshal(m proto.Message) ([]byte, error) {
+	return protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(proto.MessageV2(m))
+}
+
+func (s *JsonSerializer) Unmarshal(b []byte, m proto.Message) error {
+	if len(b) == 0 {
+		return nil
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, proto.MessageV2(m))
+}
+
+// MessagePack 序列化 (字段名与JSON序列化一致, 数值保留整数/浮点类型, int64 不转换为字符串)
+type MsgpackSerializer struct{}
+
+func (s *MsgpackSerializer) Name() string {
+	return SerializerMsgpack
+}
+
+func (s *MsgpackSerializer) Marshal(m proto.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := marshalMsgpack(msgpack.NewEncoder(&buf), proto.MessageReflect(m)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *MsgpackSerializer) Unmarshal(b []byte, m proto.Message) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if err := unmarshalMsgpack(msgpack.NewDecoder(bytes.NewReader(b)), proto.MessageReflect(m)); err != nil {
+		return errors.Invalid("msgpack unmarshal error: %s", err)
+	}
+	return nil
+}
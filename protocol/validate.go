@@ -0,0 +1,277 @@
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/tool"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// 字符串格式校验 (复用 tool.Valid() 的校验规则)
+var formats = struct {
+	sync.RWMutex
+	m map[string]func(string) bool
+}{
+	m: map[string]func(string) bool{
+		"alpha":            tool.Valid().Alpha,
+		"alphaNum":         tool.Valid().AlphaNum,
+		"alphaDash":        tool.Valid().AlphaDash,
+		"chs":              tool.Valid().Chs,
+		"chsAlpha":         tool.Valid().ChsAlpha,
+		"chsAlphaNum":      tool.Valid().ChsAlphaNum,
+		"chsDash":          tool.Valid().ChsDash,
+		"mobile":           tool.Valid().Mobile,
+		"idCard":           tool.Valid().IdCard,
+		"zip":              tool.Valid().Zip,
+		"email":            tool.Valid().Email,
+		"number":           tool.Valid().Number,
+		"integer":          tool.Valid().Integer,
+		"float":            tool.Valid().Float,
+		"positive_integer": tool.Valid().PositiveInteger,
+		"negative_integer": tool.Valid().NegativeInteger,
+	},
+}
+
+// RegisterFormat 注册字符串格式校验
+func RegisterFormat(name string, fn func(string) bool) {
+	formats.Lock()
+	defer formats.Unlock()
+
+	formats.m[name] = fn
+}
+
+func getFormat(name string) (func(string) bool, bool) {
+	formats.RLock()
+	defer formats.RUnlock()
+
+	fn, ok := formats.m[name]
+	return fn, ok
+}
+
+// Validator 请求消息实现此接口时, 在规则校验后调用
+type Validator interface {
+	Validate() error
+}
+
+// 字段校验规则
+type Rule struct {
+	required bool
+	hasRange bool
+	min, max float64
+	hasLen   bool
+	minLen   int
+	maxLen   int
+	format   string
+}
+
+// Required 必填 (非零值, 列表及map不能为空)
+func (r *Rule) Required() *Rule {
+	r.required = true
+	return r
+}
+
+// Range 数值范围 [min, max]
+func (r *Rule) Range(min, max float64) *Rule {
+	r.hasRange = true
+	r.min, r.max = min, max
+	return r
+}
+
+// Len 长度范围 [min, max] (字符串按字符计算, 列表及map按元素个数计算, max为0时不限制)
+func (r *Rule) Len(min, max int) *Rule {
+	r.hasLen = true
+	r.minLen, r.maxLen = min, max
+	return r
+}
+
+// Format 字符串格式 (如 chsAlphaNum, 参考 RegisterFormat)
+func (r *Rule) Format(name string) *Rule {
+	r.format = name
+	return r
+}
+
+// NewRule 创建字段校验规则
+func NewRule() *Rule {
+	return new(Rule)
+}
+
+// 请求消息校验规则 (key 为proto字段名)
+type Rules map[string]*Rule
+
+// 字段校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// 字段校验错误集合 (按字段编号排序)
+type FieldErrors []*FieldError
+
+func (es FieldErrors) Error() string {
+	var msgs = make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = fmt.Sprintf("%s %s", e.Field, e.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ParseFieldErrors 解析 Validate 返回错误中的字段错误 (错误详情为字段错误的JSON数组)
+func ParseFieldErrors(err error) FieldErrors {
+	e := errors.Parse(err)
+	if e == nil || e.Code != errors.CodeInvalid {
+		return nil
+	}
+	var errs FieldErrors
+	if json.Unmarshal([]byte(e.Detail), &errs) != nil {
+		return nil
+	}
+	return errs
+}
+
+// 消息校验规则 (按字段编号排序)
+type messageRules struct {
+	fields []protoreflect.FieldDescriptor
+	rules  []*Rule
+}
+
+func (mr *messageRules) Len() int { return len(mr.fields) }
+func (mr *messageRules) Less(i, j int) bool {
+	return mr.fields[i].Number() < mr.fields[j].Number()
+}
+func (mr *messageRules) Swap(i, j int) {
+	mr.fields[i], mr.fields[j] = mr.fields[j], mr.fields[i]
+	mr.rules[i], mr.rules[j] = mr.rules[j], mr.rules[i]
+}
+
+// 校验消息, 返回全部字段错误
+func (mr *messageRules) validate(m protoreflect.Message) FieldErrors {
+	var errs FieldErrors
+	for i, fd := range mr.fields {
+		if msg := checkField(mr.rules[i], m, fd); msg != "" {
+			errs = append(errs, &FieldError{Field: string(fd.Name()), Message: msg})
+		}
+	}
+	return errs
+}
+
+// SetRules 设置请求消息的校验规则 (字段名不存在或格式未注册时返回错误)
+func (r *Router) SetRules(msg proto.Message, rules Rules) error {
+	md := proto.MessageReflect(msg).Descriptor()
+
+	var errs RouteErrors
+	var mr = new(messageRules)
+	for name, rule := range rules {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			errs = append(errs, errors.Invalid("%s field %s not found", md.FullName(), name))
+			continue
+		}
+		if rule.format != "" {
+			if _, ok := getFormat(rule.format); !ok {
+				errs = append(errs, errors.Invalid("%s field %s unknown format %s", md.FullName(), name, rule.format))
+				continue
+			}
+		}
+		mr.fields = append(mr.fields, fd)
+		mr.rules = append(mr.rules, rule)
+	}
+
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	sort.Sort(mr)
+	r.rules[string(md.FullName())] = mr
+
+	return nil
+}
+
+// Validate 校验请求消息, 失败时返回 CodeInvalid 错误
+// 	规则校验失败时错误详情为字段错误的JSON数组 (按字段编号排序, 可通过 ParseFieldErrors 解析)
+// 	网关响应仅包含错误码, 需要返回字段错误时由转发服务将错误详情作为响应数据 (见 example/game/rpc/forward.go)
+func (r *Router) Validate(req proto.Message) error {
+	m := proto.MessageReflect(req)
+	if mr, ok := r.rules[string(m.Descriptor().FullName())]; ok {
+		if errs := mr.validate(m); len(errs) > 0 {
+			b, err := json.Marshal(errs)
+			if err != nil {
+				return errors.Invalid("invalid request %s: %s", m.Descriptor().FullName(), errs)
+			}
+			return errors.Invalid(string(b))
+		}
+	}
+	if v, ok := req.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return errors.Invalid("invalid request %s: %s", m.Descriptor().FullName(), err)
+		}
+	}
+	return nil
+}
+
+// 校验字段, 返回错误信息
+func checkField(rule *Rule, m protoreflect.Message, fd protoreflect.FieldDescriptor) string {
+	v := m.Get(fd)
+
+	switch {
+	case fd.IsList():
+		return checkLen(rule, v.List().Len())
+	case fd.IsMap():
+		return checkLen(rule, v.Map().Len())
+	}
+
+	if rule.required && !m.Has(fd) {
+		return "is required"
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		s := v.String()
+		if msg := checkLen(rule, utf8.RuneCountInString(s)); msg != "" {
+			return msg
+		}
+		if rule.format != "" && s != "" {
+			if fn, ok := getFormat(rule.format); ok && !fn(s) {
+				return fmt.Sprintf("format must be %s", rule.format)
+			}
+		}
+	case protoreflect.BytesKind:
+		return checkLen(rule, len(v.Bytes()))
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return checkRange(rule, float64(v.Int()))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return checkRange(rule, float64(v.Uint()))
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return checkRange(rule, v.Float())
+	case protoreflect.EnumKind:
+		return checkRange(rule, float64(v.Enum()))
+	}
+
+	return ""
+}
+
+func checkLen(rule *Rule, n int) string {
+	if rule.required && n == 0 {
+		return "is required"
+	}
+	if rule.hasLen && (n < rule.minLen || (rule.maxLen > 0 && n > rule.maxLen)) {
+		if rule.maxLen > 0 {
+			return fmt.Sprintf("length must be between %d and %d", rule.minLen, rule.maxLen)
+		}
+		return fmt.Sprintf("length must be at least %d", rule.minLen)
+	}
+	return ""
+}
+
+func checkRange(rule *Rule, n float64) string {
+	if rule.hasRange && (n < rule.min || n > rule.max) {
+		return fmt.Sprintf("must be between %v and %v", rule.min, rule.max)
+	}
+	return ""
+}
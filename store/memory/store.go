@@ -72,6 +72,18 @@ func (ms *Store) Delete(keys ...string) error {
 	return nil
 }
 
+// Purge 清理已过期的数据
+func (ms *Store) Purge() {
+	ms.Lock()
+	defer ms.Unlock()
+
+	for key, v := range ms.values {
+		if !v.CheckState() {
+			delete(ms.values, key)
+		}
+	}
+}
+
 func (ms *Store) Get(key string) (interface{}, error) {
 	ms.RLock()
 	defer ms.RUnlock()
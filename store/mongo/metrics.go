@@ -0,0 +1,79 @@
+package mongo
+
+import (
+	"github.com/cbwfree/micro-game/utils/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/event"
+	"sync/atomic"
+)
+
+// 连接池监控指标 (通过 PoolMonitor 统计)
+type poolCollector struct {
+	created  int64
+	closed   int64
+	inUse    int64
+	failed   int64
+	cleared  int64
+	conns    *prometheus.Desc
+	inUseD   *prometheus.Desc
+	createdD *prometheus.Desc
+	closedD  *prometheus.Desc
+	failedD  *prometheus.Desc
+	clearedD *prometheus.Desc
+}
+
+// 连接池事件
+func (c *poolCollector) event(e *event.PoolEvent) {
+	switch e.Type {
+	case event.ConnectionCreated:
+		atomic.AddInt64(&c.created, 1)
+	case event.ConnectionClosed:
+		atomic.AddInt64(&c.closed, 1)
+	case event.GetSucceeded:
+		atomic.AddInt64(&c.inUse, 1)
+	case event.ConnectionReturned:
+		atomic.AddInt64(&c.inUse, -1)
+	case event.GetFailed:
+		atomic.AddInt64(&c.failed, 1)
+	case event.PoolCleared:
+		atomic.AddInt64(&c.cleared, 1)
+	}
+}
+
+func (c *poolCollector) Monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{Event: c.event}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.conns
+	ch <- c.inUseD
+	ch <- c.createdD
+	ch <- c.closedD
+	ch <- c.failedD
+	ch <- c.clearedD
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	created, closed := atomic.LoadInt64(&c.created), atomic.LoadInt64(&c.closed)
+	ch <- prometheus.MustNewConstMetric(c.conns, prometheus.GaugeValue, float64(created-closed))
+	ch <- prometheus.MustNewConstMetric(c.inUseD, prometheus.GaugeValue, float64(atomic.LoadInt64(&c.inUse)))
+	ch <- prometheus.MustNewConstMetric(c.createdD, prometheus.CounterValue, float64(created))
+	ch <- prometheus.MustNewConstMetric(c.closedD, prometheus.CounterValue, float64(closed))
+	ch <- prometheus.MustNewConstMetric(c.failedD, prometheus.CounterValue, float64(atomic.LoadInt64(&c.failed)))
+	ch <- prometheus.MustNewConstMetric(c.clearedD, prometheus.CounterValue, float64(atomic.LoadInt64(&c.cleared)))
+}
+
+func newPoolCollector(dbName string) *poolCollector {
+	labels := prometheus.Labels{"db": dbName}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "mongo_pool", name), help, nil, labels)
+	}
+	return &poolCollector{
+		conns:    desc("connections", "Open connections in the pool."),
+		inUseD:   desc("in_use_connections", "Connections checked out of the pool."),
+		createdD: desc("created_total", "Connections created."),
+		closedD:  desc("closed_total", "Connections closed."),
+		failedD:  desc("checkout_failed_total", "Failed connection checkouts."),
+		clearedD: desc("cleared_total", "Pool clears."),
+	}
+}
@@ -9,7 +9,9 @@ package mongo
 import (
 	"context"
 	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/health"
 	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/cbwfree/micro-game/utils/metrics"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -20,10 +22,11 @@ import (
 
 // MongoDB 数据存储
 type Store struct {
-	opts   *Options
-	ctx    context.Context
-	cancel context.CancelFunc
-	client *mongo.Client
+	opts    *Options
+	ctx     context.Context
+	cancel  context.CancelFunc
+	client  *mongo.Client
+	metrics *poolCollector
 }
 
 func (s *Store) Init(opts ...Option) {
@@ -51,7 +54,10 @@ func (s *Store) Connect() error {
 		s.opts.RawUrl = "mongodb://" + s.opts.RawUrl
 	}
 
+	s.metrics = newPoolCollector(s.opts.DbName)
+
 	opts := options.Client().
+		SetPoolMonitor(s.metrics.Monitor()).
 		SetMinPoolSize(s.opts.MinPoolSize).
 		SetMaxPoolSize(s.opts.MaxPoolSize).
 		SetConnectTimeout(s.opts.ConnectTimeout).
@@ -78,6 +84,14 @@ func (s *Store) Connect() error {
 		return err
 	}
 
+	// 健康检查
+	health.Register(s.healthName(), s.Health)
+
+	// 连接池监控指标
+	if err := metrics.Register(s.metrics); err != nil {
+		log.Warn("Store [mongodb] register metrics error: %s", err)
+	}
+
 	log.Debug("Store [mongodb] Connect to %s", s.opts.RawUrl)
 
 	return nil
@@ -88,15 +102,34 @@ func (s *Store) Disconnect() error {
 		return nil
 	}
 
+	health.Unregister(s.healthName())
+
 	if err := s.client.Disconnect(s.ctx); err != nil {
 		return err
 	}
 
 	s.client = nil
 
+	if s.metrics != nil {
+		metrics.Unregister(s.metrics)
+		s.metrics = nil
+	}
+
 	return nil
 }
 
+// Health 健康检查 (检查主节点连接)
+func (s *Store) Health(ctx context.Context) error {
+	if s.client == nil {
+		return errors.Unavailable("mongodb is not connected")
+	}
+	return s.client.Ping(ctx, readpref.Primary())
+}
+
+func (s *Store) healthName() string {
+	return "mongo:" + s.opts.DbName
+}
+
 // Client 获取客户端
 func (s *Store) Client() *mongo.Client {
 	return s.client
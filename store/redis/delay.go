@@ -0,0 +1,337 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/go-redis/redis/v8"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDelayInterval = 500 * time.Millisecond // 默认轮询间隔
+	DefaultDelayBatch    = 100                    // 默认单次领取数量
+	DefaultDelayLease    = 30 * time.Second       // 默认领取租约 (超时未确认时重新投递)
+)
+
+// 领取到期消息: 将分数延后为租约到期时间并累加投递次数, 返回 [id, data, attempts, id, data, attempts ...]
+// 	KEYS: 队列有序集合, 数据哈希表, 投递次数哈希表 (所有键均通过 KEYS 传入)
+var delayClaimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+local res = {}
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[3], id)
+	table.insert(res, id)
+	table.insert(res, redis.call('HGET', KEYS[2], id))
+	table.insert(res, redis.call('HINCRBY', KEYS[3], id, 1))
+end
+return res
+`)
+
+// 延迟消息
+type DelayMessage struct {
+	Id          string            `json:"id"`
+	Topic       string            `json:"topic"`
+	Type        string            `json:"type,omitempty"`        // 消息类型
+	ContentType string            `json:"contentType,omitempty"` // 消息编码类型
+	Body        []byte            `json:"body"`
+	Header      map[string]string `json:"header,omitempty"`   // 消息头 (投递时设置)
+	Due         int64             `json:"due"`                // 投递时间 (毫秒)
+	Attempts    int               `json:"attempts,omitempty"` // 投递次数 (领取时设置, 不保存)
+}
+
+// DelayHandler 延迟消息处理 (返回错误时, 租约到期后重新投递)
+// 	启用 WithDelayManualAck 时, 处理成功后仍需调用 Ack 确认, 否则租约到期后重新投递
+type DelayHandler func(ctx context.Context, msg *DelayMessage) error
+
+type DelayOption func(o *DelayOptions)
+
+type DelayOptions struct {
+	Interval    time.Duration // 轮询间隔
+	Batch       int           // 单次领取数量
+	Lease       time.Duration // 领取租约
+	ManualAck   bool          // 处理成功后不删除消息, 由 Ack 确认
+	MaxAttempts int           // 最大投递次数 (超过时丢弃消息), 为0不限制
+}
+
+func WithDelayInterval(d time.Duration) DelayOption {
+	return func(o *DelayOptions) {
+		o.Interval = d
+	}
+}
+
+func WithDelayBatch(n int) DelayOption {
+	return func(o *DelayOptions) {
+		o.Batch = n
+	}
+}
+
+func WithDelayLease(d time.Duration) DelayOption {
+	return func(o *DelayOptions) {
+		o.Lease = d
+	}
+}
+
+// WithDelayManualAck 消息由接收方处理完成后调用 Ack 确认 (如投递到消息代理后, 由订阅者确认)
+func WithDelayManualAck() DelayOption {
+	return func(o *DelayOptions) {
+		o.ManualAck = true
+	}
+}
+
+func WithDelayMaxAttempts(n int) DelayOption {
+	return func(o *DelayOptions) {
+		o.MaxAttempts = n
+	}
+}
+
+// Scheduler 延迟消息调度器
+// 	消息保存在有序集合 (分数为投递时间) 及哈希表中, 服务重启后继续投递
+// 	多个节点可同时运行, 到期消息由单个节点领取, 确认后删除, 未确认的消息在租约到期后重新投递 (至少投递一次)
+// 	默认处理函数返回成功即确认, 启用 WithDelayManualAck 时由接收方调用 Ack 确认
+type Scheduler struct {
+	sync.Mutex
+	store   *Store
+	name    string
+	opts    *DelayOptions
+	handler DelayHandler
+	exit    chan struct{}
+	done    chan struct{}
+}
+
+// 调度器的键使用相同的 {hash-tag}, Redis Cluster 中位于同一槽位 (脚本及事务可同时操作)
+func (d *Scheduler) key(suffix string) string {
+	return "delay:{" + d.name + "}:" + suffix
+}
+
+func (d *Scheduler) queueKey() string {
+	return d.key("queue")
+}
+
+func (d *Scheduler) dataKey() string {
+	return d.key("data")
+}
+
+func (d *Scheduler) attemptsKey() string {
+	return d.key("attempts")
+}
+
+func (d *Scheduler) client() (*redis.Client, error) {
+	if d.store.Client() == nil {
+		return nil, errors.Unavailable("redis is not connected")
+	}
+	return d.store.Client(), nil
+}
+
+// Add 添加延迟消息 (相同ID时覆盖)
+func (d *Scheduler) Add(ctx context.Context, msg *DelayMessage) error {
+	c, err := d.client()
+	if err != nil {
+		return err
+	}
+	if msg.Id == "" {
+		return errors.Invalid("delay message id is empty")
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	pipe := c.TxPipeline()
+	pipe.HSet(ctx, d.dataKey(), msg.Id, data)
+	pipe.ZAdd(ctx, d.queueKey(), &redis.Z{Score: float64(msg.Due), Member: msg.Id})
+	pipe.HDel(ctx, d.attemptsKey(), msg.Id)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Cancel 取消延迟消息, 返回消息是否存在
+func (d *Scheduler) Cancel(ctx context.Context, id string) (bool, error) {
+	c, err := d.client()
+	if err != nil {
+		return false, err
+	}
+
+	pipe := c.TxPipeline()
+	rem := pipe.ZRem(ctx, d.queueKey(), id)
+	pipe.HDel(ctx, d.dataKey(), id)
+	pipe.HDel(ctx, d.attemptsKey(), id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return rem.Val() > 0, nil
+}
+
+// Ack 确认消息已处理, 删除消息 (消息不存在时忽略)
+func (d *Scheduler) Ack(ctx context.Context, id string) error {
+	_, err := d.Cancel(ctx, id)
+	return err
+}
+
+// Get 获取延迟消息
+func (d *Scheduler) Get(ctx context.Context, id string) (*DelayMessage, error) {
+	c, err := d.client()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.HGet(ctx, d.dataKey(), id).Bytes()
+	if err == redis.Nil {
+		return nil, errors.NotFound("delay message %s not found", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	msg := new(DelayMessage)
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Len 待投递消息数量
+func (d *Scheduler) Len(ctx context.Context) (int64, error) {
+	c, err := d.client()
+	if err != nil {
+		return 0, err
+	}
+	return c.ZCard(ctx, d.queueKey()).Result()
+}
+
+// Poll 领取并处理到期消息, 返回领取数量
+// 	处理成功后确认 (启用 WithDelayManualAck 时等待 Ack), 处理失败或未确认的消息在租约到期后重新投递
+func (d *Scheduler) Poll(ctx context.Context) (int, error) {
+	c, err := d.client()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	val, err := delayClaimScript.Run(ctx, c,
+		[]string{d.queueKey(), d.dataKey(), d.attemptsKey()},
+		now.UnixNano()/1e6, d.opts.Batch, now.Add(d.opts.Lease).UnixNano()/1e6,
+	).Result()
+	if err != nil {
+		return 0, err
+	}
+	res, _ := val.([]interface{})
+
+	for i := 0; i+2 < len(res); i += 3 {
+		id, _ := res[i].(string)
+		data, _ := res[i+1].(string)
+		attempts, _ := res[i+2].(int64)
+
+		msg := new(DelayMessage)
+		if data == "" {
+			// 数据已删除 (取消), 清理残留
+			_, _ = d.Cancel(ctx, id)
+			continue
+		}
+		if err := json.Unmarshal([]byte(data), msg); err != nil {
+			log.Error("[Delay] invalid message [%s]: %s", id, err)
+			_, _ = d.Cancel(ctx, id)
+			continue
+		}
+		msg.Attempts = int(attempts)
+
+		if d.opts.MaxAttempts > 0 && msg.Attempts > d.opts.MaxAttempts {
+			log.Error("[Delay] message [%s] topic [%s] not acked after %d attempts, dropped", id, msg.Topic, d.opts.MaxAttempts)
+			_, _ = d.Cancel(ctx, id)
+			continue
+		}
+
+		if err := d.handler(ctx, msg); err != nil {
+			log.Warn("[Delay] handle message [%s] topic [%s] error: %s", id, msg.Topic, err)
+			continue
+		}
+		if d.opts.ManualAck {
+			continue
+		}
+
+		if err := d.Ack(ctx, id); err != nil {
+			log.Warn("[Delay] ack message [%s] error: %s", id, err)
+		}
+	}
+
+	return len(res) / 3, nil
+}
+
+// Start 启动调度
+func (d *Scheduler) Start() {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.exit != nil {
+		return
+	}
+	d.exit = make(chan struct{})
+	d.done = make(chan struct{})
+
+	go d.run(d.exit, d.done)
+}
+
+// Stop 停止调度
+func (d *Scheduler) Stop() {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.exit == nil {
+		return
+	}
+	close(d.exit)
+	<-d.done
+	d.exit, d.done = nil, nil
+}
+
+func (d *Scheduler) run(exit, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(d.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-ticker.C:
+		}
+
+		// 领取数量达到上限时继续领取
+		for {
+			n, err := d.Poll(context.Background())
+			if err != nil {
+				log.Warn("[Delay] poll [%s] error: %s", d.name, err)
+			}
+			if err != nil || n < d.opts.Batch {
+				break
+			}
+			select {
+			case <-exit:
+				return
+			default:
+			}
+		}
+	}
+}
+
+// NewScheduler 创建延迟消息调度器 (相同名称的调度器共用消息队列)
+func NewScheduler(s *Store, name string, handler DelayHandler, opts ...DelayOption) *Scheduler {
+	o := &DelayOptions{
+		Interval: DefaultDelayInterval,
+		Batch:    DefaultDelayBatch,
+		Lease:    DefaultDelayLease,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Scheduler{
+		store:   s,
+		name:    name,
+		opts:    o,
+		handler: handler,
+	}
+}
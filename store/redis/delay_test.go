@@ -0,0 +1,142 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	rs := NewStore(WithUrl(mr.Addr()))
+	if err := rs.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Disconnect()
+
+	var handled []string
+	var fail = true
+	d := NewScheduler(rs, "test", func(_ context.Context, msg *DelayMessage) error {
+		handled = append(handled, msg.Id)
+		if msg.Id == "retry" && fail {
+			fail = false
+			return errors.Server("handle failed")
+		}
+		return nil
+	}, WithDelayLease(50*time.Millisecond))
+
+	ctx := context.Background()
+	now := time.Now().UnixNano() / 1e6
+	for _, msg := range []*DelayMessage{
+		{Id: "due", Topic: "test", Body: []byte("1"), Due: now},
+		{Id: "retry", Topic: "test", Due: now},
+		{Id: "later", Topic: "test", Due: now + 60000},
+		{Id: "cancel", Topic: "test", Due: now},
+	} {
+		if err := d.Add(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ok, err := d.Cancel(ctx, "cancel"); err != nil || !ok {
+		t.Fatalf("cancel: %v, %v", ok, err)
+	}
+	if msg, err := d.Get(ctx, "due"); err != nil || string(msg.Body) != "1" {
+		t.Fatalf("get: %+v, %v", msg, err)
+	}
+
+	if n, err := d.Poll(ctx); err != nil || n != 2 {
+		t.Fatalf("poll: %d, %v", n, err)
+	}
+	if n, _ := d.Len(ctx); n != 2 {
+		t.Fatalf("pending %d, expected 2", n)
+	}
+
+	// 处理失败的消息在租约到期前不会重新投递
+	if n, _ := d.Poll(ctx); n != 0 {
+		t.Fatalf("poll leased: %d", n)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if n, _ := d.Poll(ctx); n != 1 {
+		t.Fatalf("poll after lease: %d", n)
+	}
+
+	if n, _ := d.Len(ctx); n != 1 {
+		t.Fatalf("pending %d, expected 1", n)
+	}
+	if len(handled) != 3 || handled[2] != "retry" {
+		t.Fatalf("handled: %v", handled)
+	}
+}
+
+func TestScheduler_ManualAck(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	rs := NewStore(WithUrl(mr.Addr()))
+	if err := rs.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Disconnect()
+
+	var handled []int
+	d := NewScheduler(rs, "ack", func(_ context.Context, msg *DelayMessage) error {
+		handled = append(handled, msg.Attempts)
+		return nil
+	}, WithDelayLease(50*time.Millisecond), WithDelayManualAck(), WithDelayMaxAttempts(2))
+
+	ctx := context.Background()
+	now := time.Now().UnixNano() / 1e6
+	for _, id := range []string{"acked", "dropped"} {
+		if err := d.Add(ctx, &DelayMessage{Id: id, Topic: "test", Due: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 处理成功但未确认的消息在租约到期后重新投递
+	if n, err := d.Poll(ctx); err != nil || n != 2 {
+		t.Fatalf("poll: %d, %v", n, err)
+	}
+	if err := d.Ack(ctx, "acked"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if n, _ := d.Poll(ctx); n != 1 {
+		t.Fatalf("poll after lease: %d", n)
+	}
+
+	// 超过最大投递次数时丢弃
+	time.Sleep(60 * time.Millisecond)
+	if n, _ := d.Poll(ctx); n != 1 {
+		t.Fatalf("poll dropped: %d", n)
+	}
+	if n, _ := d.Len(ctx); n != 0 {
+		t.Fatalf("pending %d, expected 0", n)
+	}
+	if len(handled) != 3 || handled[0] != 1 || handled[2] != 2 {
+		t.Fatalf("handled attempts: %v", handled)
+	}
+}
+
+// 调度器的键位于同一 Redis Cluster 槽位 (按首个 {...} 内容计算槽位)
+func TestScheduler_KeySlot(t *testing.T) {
+	d := NewScheduler(nil, "slot", nil)
+	for _, key := range []string{d.queueKey(), d.dataKey(), d.attemptsKey()} {
+		start := strings.Index(key, "{")
+		end := strings.Index(key[start+1:], "}")
+		if start < 0 || end <= 0 || key[start+1:start+1+end] != "slot" {
+			t.Fatalf("key %s without hash tag", key)
+		}
+	}
+}
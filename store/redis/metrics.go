@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"github.com/cbwfree/micro-game/utils/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 连接池监控指标
+type poolCollector struct {
+	store  *Store
+	hits   *prometheus.Desc
+	misses *prometheus.Desc
+	waits  *prometheus.Desc
+	total  *prometheus.Desc
+	idle   *prometheus.Desc
+	stale  *prometheus.Desc
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.waits
+	ch <- c.total
+	ch <- c.idle
+	ch <- c.stale
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	client := c.store.client
+	if client == nil {
+		return
+	}
+	stats := client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.waits, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.stale, prometheus.CounterValue, float64(stats.StaleConns))
+}
+
+func newPoolCollector(s *Store, addr string) *poolCollector {
+	labels := prometheus.Labels{"addr": addr}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "redis_pool", name), help, nil, labels)
+	}
+	return &poolCollector{
+		store:  s,
+		hits:   desc("hits_total", "Free connection found in the pool."),
+		misses: desc("misses_total", "Free connection not found in the pool."),
+		waits:  desc("timeouts_total", "Wait timeouts for a connection."),
+		total:  desc("connections", "Total connections in the pool."),
+		idle:   desc("idle_connections", "Idle connections in the pool."),
+		stale:  desc("stale_total", "Stale connections removed from the pool."),
+	}
+}
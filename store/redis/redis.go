@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"github.com/bsm/redislock"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/health"
 	"github.com/cbwfree/micro-game/utils/log"
+	"github.com/cbwfree/micro-game/utils/metrics"
 	"github.com/go-redis/redis/v8"
 	"strings"
 )
@@ -15,9 +18,10 @@ var (
 
 // Redis 存储
 type Store struct {
-	opts   *Options
-	client *redis.Client
-	locker *redislock.Client
+	opts    *Options
+	client  *redis.Client
+	locker  *redislock.Client
+	metrics *poolCollector
 }
 
 func (s *Store) Init(opts ...Option) {
@@ -73,6 +77,15 @@ func (s *Store) Connect() error {
 	// 启用分布式锁
 	s.locker = redislock.New(s.client)
 
+	// 健康检查
+	health.Register(s.healthName(), s.Health)
+
+	// 连接池监控指标
+	s.metrics = newPoolCollector(s, opts.Addr)
+	if err := metrics.Register(s.metrics); err != nil {
+		log.Warn("Store [redis] register metrics error: %s", err)
+	}
+
 	log.Debug("Store [redis] Connect to %s", opts.Addr)
 
 	return nil
@@ -80,14 +93,31 @@ func (s *Store) Connect() error {
 
 func (s *Store) Disconnect() error {
 	if s.client != nil {
+		health.Unregister(s.healthName())
 		if err := s.client.Close(); err != nil {
 			return err
 		}
 		s.client = nil
 	}
+	if s.metrics != nil {
+		metrics.Unregister(s.metrics)
+		s.metrics = nil
+	}
 	return nil
 }
 
+// Health 健康检查
+func (s *Store) Health(ctx context.Context) error {
+	if s.client == nil {
+		return errors.Unavailable("redis is not connected")
+	}
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *Store) healthName() string {
+	return "redis:" + s.client.Options().Addr
+}
+
 // Do 执行命令
 func (s *Store) Do(args ...interface{}) *redis.Cmd {
 	ctx := context.Background()
@@ -2,6 +2,10 @@ package redis
 
 import (
 	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/cbwfree/micro-game/utils/metrics"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -19,3 +23,26 @@ func TestStore_Connect(t *testing.T) {
 
 	fmt.Printf("redis连接成功\n")
 }
+
+// 服务启动前连接的存储, 在启用监控指标后导出连接池指标
+func TestStore_MetricsBeforeEnable(t *testing.T) {
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mr.Close()
+
+	rs := NewStore(WithUrl(mr.Addr()))
+	if err := rs.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Disconnect()
+
+	metrics.Enable("test")
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if body := rec.Body.String(); !strings.Contains(body, `game_redis_pool_connections{addr="`+mr.Addr()+`",service="test"}`) {
+		t.Fatalf("redis pool metrics not found:\n%s", body)
+	}
+}
@@ -47,6 +47,17 @@ func New(code int32, a ...interface{}) *Error {
 	return e
 }
 
+// Recover 将 panic 的值转换为服务器错误, 并始终记录堆栈信息
+func Recover(r interface{}) *Error {
+	e := &Error{
+		Code:   CodeServerError,
+		Detail: fmt.Sprintf("panic: %v", r),
+		Status: StatusText(CodeServerError),
+	}
+	e.stack = callers()
+	return e
+}
+
 type Error struct {
 	Code   int32  `json:"code"`
 	Status string `json:"status"`
@@ -0,0 +1,121 @@
+// 一致性哈希环 (虚拟节点)
+package hashring
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// 默认每个节点的虚拟节点数量
+const DefaultReplicas = 160
+
+// Ring 一致性哈希环
+type Ring struct {
+	sync.RWMutex
+	replicas int
+	hashes   []uint32          // 虚拟节点哈希 (有序)
+	nodes    map[uint32]string // 虚拟节点哈希 => 节点
+	members  map[string]bool   // 节点列表
+}
+
+func hash(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+// Add 添加节点
+func (r *Ring) Add(nodes ...string) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, node := range nodes {
+		if r.members[node] {
+			continue
+		}
+		r.members[node] = true
+		for i := 0; i < r.replicas; i++ {
+			h := hash(strconv.Itoa(i) + node)
+			if _, ok := r.nodes[h]; ok {
+				continue
+			}
+			r.nodes[h] = node
+			r.hashes = append(r.hashes, h)
+		}
+	}
+
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// Remove 移除节点
+func (r *Ring) Remove(node string) {
+	r.Lock()
+	defer r.Unlock()
+
+	if !r.members[node] {
+		return
+	}
+	delete(r.members, node)
+
+	hashes := r.hashes[:0]
+	for _, h := range r.hashes {
+		if r.nodes[h] == node {
+			delete(r.nodes, h)
+			continue
+		}
+		hashes = append(hashes, h)
+	}
+	r.hashes = hashes
+}
+
+// Get 获取键对应的节点 (环为空时返回空字符串)
+func (r *Ring) Get(key string) string {
+	r.RLock()
+	defer r.RUnlock()
+
+	if len(r.hashes) == 0 {
+		return ""
+	}
+
+	h := hash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.nodes[r.hashes[i]]
+}
+
+// Len 节点数量
+func (r *Ring) Len() int {
+	r.RLock()
+	defer r.RUnlock()
+
+	return len(r.members)
+}
+
+// Nodes 节点列表
+func (r *Ring) Nodes() []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	var nodes = make([]string, 0, len(r.members))
+	for node := range r.members {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// New 创建一致性哈希环 (replicas 为每个节点的虚拟节点数量, <=0 时使用默认值)
+func New(replicas int, nodes ...string) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	r := &Ring{
+		replicas: replicas,
+		nodes:    make(map[uint32]string),
+		members:  make(map[string]bool),
+	}
+	r.Add(nodes...)
+	return r
+}
@@ -0,0 +1,70 @@
+package hashring
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+func TestRing_Get(t *testing.T) {
+	r := New(0)
+	if r.Get("1") != "" {
+		t.Fatal("empty ring should return empty node")
+	}
+
+	var nodes []string
+	for i := 0; i < 10; i++ {
+		nodes = append(nodes, fmt.Sprintf("game-%d", i))
+	}
+	r.Add(nodes...)
+	r.Add(nodes[0])
+	if r.Len() != 10 {
+		t.Fatalf("ring len %d", r.Len())
+	}
+
+	const keys = 100000
+	var before = make(map[string]string, keys)
+	var counts = make(map[string]int)
+	for i := 0; i < keys; i++ {
+		key := strconv.Itoa(i)
+		node := r.Get(key)
+		if r.Get(key) != node {
+			t.Fatalf("key %s not stable", key)
+		}
+		before[key] = node
+		counts[node]++
+	}
+
+	// 分布均匀 (每个节点 5% ~ 15%)
+	for node, n := range counts {
+		if n < keys/20 || n > keys*3/20 {
+			t.Fatalf("node %s keys %d not balanced: %v", node, n, counts)
+		}
+	}
+
+	// 移除节点, 仅该节点的键重新映射
+	r.Remove(nodes[3])
+	var moved int
+	for key, node := range before {
+		now := r.Get(key)
+		if node == nodes[3] {
+			if now == nodes[3] {
+				t.Fatalf("key %s still on removed node", key)
+			}
+			moved++
+		} else if now != node {
+			t.Fatalf("key %s moved from %s to %s", key, node, now)
+		}
+	}
+	if moved != counts[nodes[3]] {
+		t.Fatalf("moved %d, expected %d", moved, counts[nodes[3]])
+	}
+
+	// 重新加入节点, 恢复原映射
+	r.Add(nodes[3])
+	for key, node := range before {
+		if r.Get(key) != node {
+			t.Fatalf("key %s not restored", key)
+		}
+	}
+}
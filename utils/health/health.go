@@ -0,0 +1,152 @@
+// 服务健康检查
+//
+// 组件 (存储、网关等) 注册检查函数, 就绪检查时并发执行全部检查函数.
+package health
+
+import (
+	"context"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultTimeout = 3 * time.Second // 默认单项检查超时时间
+)
+
+// 状态
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// 检查函数 (返回nil表示正常)
+type Checker func(ctx context.Context) error
+
+var (
+	mu     sync.RWMutex
+	checks = make(map[string]Checker)
+	ready  bool
+)
+
+// Register 注册检查 (同名时覆盖)
+func Register(name string, check Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	checks[name] = check
+}
+
+// Unregister 注销检查
+func Unregister(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(checks, name)
+}
+
+// Names 已注册的检查名称
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var names = make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetReady 设置服务就绪状态 (服务启动完成后设置为true, 停止前设置为false)
+func SetReady(b bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ready = b
+}
+
+// IsReady 服务是否已就绪 (不执行检查)
+func IsReady() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return ready
+}
+
+// 检查结果
+type Report struct {
+	Status string            `json:"status"`           // 总体状态
+	Ready  bool              `json:"ready"`            // 服务是否已就绪
+	Checks map[string]string `json:"checks,omitempty"` // 各项检查状态 (失败时为错误信息)
+}
+
+// Up 是否正常
+func (r *Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+// Check 执行全部检查
+func Check(ctx context.Context) *Report {
+	mu.RLock()
+	var list = make(map[string]Checker, len(checks))
+	for name, check := range checks {
+		list[name] = check
+	}
+	r := &Report{
+		Status: StatusUp,
+		Ready:  ready,
+		Checks: make(map[string]string, len(list)),
+	}
+	mu.RUnlock()
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	for name, check := range list {
+		wg.Add(1)
+		go func(name string, check Checker) {
+			defer wg.Done()
+
+			status := StatusUp
+			if err := run(ctx, check); err != nil {
+				status = err.Error()
+			}
+
+			lock.Lock()
+			r.Checks[name] = status
+			lock.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	if !r.Ready {
+		r.Status = StatusDown
+	}
+	for _, status := range r.Checks {
+		if status != StatusUp {
+			r.Status = StatusDown
+		}
+	}
+
+	return r
+}
+
+// CheckOne 执行单项检查 (检查不存在时返回 NotFound 错误)
+func CheckOne(ctx context.Context, name string) error {
+	mu.RLock()
+	check, ok := checks[name]
+	mu.RUnlock()
+
+	if !ok {
+		return errors.NotFound("health check %s not found", name)
+	}
+	return run(ctx, check)
+}
+
+func run(ctx context.Context, check Checker) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	return check(ctx)
+}
@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	SetReady(true)
+	Register("ok", func(ctx context.Context) error { return nil })
+	defer Unregister("ok")
+
+	if r := Check(context.Background()); !r.Up() || r.Checks["ok"] != StatusUp {
+		t.Fatalf("expected up: %+v", r)
+	}
+
+	Register("fail", func(ctx context.Context) error { return errors.New("connection refused") })
+	r := Check(context.Background())
+	if r.Up() || r.Checks["fail"] != "connection refused" {
+		t.Fatalf("expected down: %+v", r)
+	}
+	Unregister("fail")
+
+	// 未就绪时状态为 down
+	SetReady(false)
+	if r := Check(context.Background()); r.Up() {
+		t.Fatalf("expected down when not ready: %+v", r)
+	}
+
+	if err := CheckOne(context.Background(), "unknown"); err == nil {
+		t.Fatal("expected unknown check error")
+	}
+}
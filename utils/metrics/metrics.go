@@ -0,0 +1,229 @@
+// Prometheus 监控指标
+//
+// 通过 Enable 启用后开始采集, 未启用时各采集函数直接返回, 不产生额外开销.
+package metrics
+
+import (
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const Namespace = "game"
+
+// 消息方向
+const (
+	DirIn  = "in"  // 接收
+	DirOut = "out" // 发送
+)
+
+var (
+	enabled    int32
+	mu         sync.Mutex
+	registry   = prometheus.NewRegistry()
+	registerer prometheus.Registerer
+	pending    []prometheus.Collector // 启用前注册的采集器 (启用时注册)
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "rpc",
+		Name:      "duration_seconds",
+		Help:      "RPC handler latency in seconds.",
+	}, []string{"endpoint"})
+	rpcErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "rpc",
+		Name:      "errors_total",
+		Help:      "RPC handler errors by code.",
+	}, []string{"endpoint", "code"})
+
+	subDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "subscriber",
+		Name:      "duration_seconds",
+		Help:      "Subscriber handler latency in seconds.",
+	}, []string{"topic"})
+	subErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "subscriber",
+		Name:      "errors_total",
+		Help:      "Subscriber handler errors by code.",
+	}, []string{"topic", "code"})
+
+	agentConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "agent",
+		Name:      "connections",
+		Help:      "Current agent client connections.",
+	}, []string{"type"})
+	agentMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "agent",
+		Name:      "messages_total",
+		Help:      "Agent messages by direction.",
+	}, []string{"dir"})
+	agentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "agent",
+		Name:      "bytes_total",
+		Help:      "Agent message bytes (including head) by direction.",
+	}, []string{"dir"})
+
+	routeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "protocol",
+		Name:      "duration_seconds",
+		Help:      "Protocol handler latency in seconds.",
+	}, []string{"cmd"})
+	routeCodes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "protocol",
+		Name:      "responses_total",
+		Help:      "Protocol responses by code (0 is success).",
+	}, []string{"cmd", "code"})
+)
+
+// Enable 启用指标采集 (所有指标附加 service 标签)
+func Enable(service string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if registerer != nil {
+		return
+	}
+
+	registerer = prometheus.WrapRegistererWith(prometheus.Labels{"service": service}, registry)
+	registerer.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		rpcDuration, rpcErrors,
+		subDuration, subErrors,
+		agentConnections, agentMessages, agentBytes,
+		routeDuration, routeCodes,
+	)
+
+	// 注册启用前的采集器 (如服务启动前连接的存储连接池)
+	for _, c := range pending {
+		_ = register(c)
+	}
+	pending = nil
+
+	atomic.StoreInt32(&enabled, 1)
+}
+
+// Enabled 是否已启用
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// Register 注册采集器 (未启用时在启用后注册, 重复注册时不返回错误)
+func Register(c prometheus.Collector) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if registerer == nil {
+		for _, p := range pending {
+			if p == c {
+				return nil
+			}
+		}
+		pending = append(pending, c)
+		return nil
+	}
+
+	return register(c)
+}
+
+func register(c prometheus.Collector) error {
+	if err := registerer.Register(c); err != nil {
+		if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unregister 注销采集器
+func Unregister(c prometheus.Collector) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if registerer != nil {
+		registerer.Unregister(c)
+		return
+	}
+
+	for i, p := range pending {
+		if p == c {
+			pending = append(pending[:i], pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// Handler 指标导出 HTTP 处理
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// 错误码 (成功时为0)
+func errCode(err error) string {
+	if err == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(errors.Parse(err).Code))
+}
+
+// ObserveRPC 记录RPC处理
+func ObserveRPC(endpoint string, d time.Duration, err error) {
+	if !Enabled() {
+		return
+	}
+	rpcDuration.WithLabelValues(endpoint).Observe(d.Seconds())
+	if err != nil {
+		rpcErrors.WithLabelValues(endpoint, errCode(err)).Inc()
+	}
+}
+
+// ObserveSubscriber 记录订阅处理
+func ObserveSubscriber(topic string, d time.Duration, err error) {
+	if !Enabled() {
+		return
+	}
+	subDuration.WithLabelValues(topic).Observe(d.Seconds())
+	if err != nil {
+		subErrors.WithLabelValues(topic, errCode(err)).Inc()
+	}
+}
+
+// ObserveRoute 记录协议处理
+func ObserveRoute(cmd uint32, d time.Duration, err error) {
+	if !Enabled() {
+		return
+	}
+	c := strconv.FormatUint(uint64(cmd), 10)
+	routeDuration.WithLabelValues(c).Observe(d.Seconds())
+	routeCodes.WithLabelValues(c, errCode(err)).Inc()
+}
+
+// AgentConnection 记录网关连接数变化
+func AgentConnection(typ string, delta int) {
+	if !Enabled() {
+		return
+	}
+	agentConnections.WithLabelValues(typ).Add(float64(delta))
+}
+
+// AgentMessage 记录网关消息
+func AgentMessage(dir string, size int) {
+	if !Enabled() {
+		return
+	}
+	agentMessages.WithLabelValues(dir).Inc()
+	agentBytes.WithLabelValues(dir).Add(float64(size))
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cbwfree/micro-game/utils/errors"
+)
+
+func TestMetrics(t *testing.T) {
+	// 未启用时不采集
+	ObserveRoute(10001, time.Millisecond, nil)
+
+	Enable("test")
+	ObserveRPC("Forward.Protocol", time.Millisecond, nil)
+	ObserveRoute(10001, time.Millisecond, nil)
+	ObserveRoute(10001, time.Millisecond, errors.Invalid("bad request"))
+	AgentConnection("tcp", 1)
+	AgentMessage(DirIn, 16)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	b, _ := ioutil.ReadAll(rec.Body)
+	body := string(b)
+
+	for _, s := range []string{
+		`game_rpc_duration_seconds_count{endpoint="Forward.Protocol",service="test"} 1`,
+		`game_protocol_responses_total{cmd="10001",code="0",service="test"} 1`,
+		`game_protocol_responses_total{cmd="10001",code="3",service="test"} 1`,
+		`game_agent_connections{service="test",type="tcp"} 1`,
+		`game_agent_bytes_total{dir="in",service="test"} 16`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("metric not found: %s", s)
+		}
+	}
+}
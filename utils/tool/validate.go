@@ -84,7 +84,7 @@ func (v *validate) Mobile(val string) bool {
 
 // 检查身份证号码是否正确
 func (v *validate) IdCard(val string) bool {
-	return regexp.MustCompile(validateRules["idcard"]).MatchString(val)
+	return regexp.MustCompile(validateRules["idCard"]).MatchString(val)
 }
 
 // 检查邮编是否正确
@@ -0,0 +1,62 @@
+package trace
+
+import (
+	"sync"
+)
+
+// 跨度导出器
+type Exporter interface {
+	Export(span *Span) // 导出跨度 (不能阻塞)
+	Close() error      // 关闭导出器 (导出剩余跨度)
+}
+
+// MemoryExporter 内存导出器 (用于测试)
+type MemoryExporter struct {
+	sync.Mutex
+	spans []*Span
+}
+
+func (e *MemoryExporter) Export(span *Span) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.spans = append(e.spans, span)
+}
+
+func (e *MemoryExporter) Close() error {
+	return nil
+}
+
+// Spans 已导出的跨度 (按结束顺序)
+func (e *MemoryExporter) Spans() []*Span {
+	e.Lock()
+	defer e.Unlock()
+
+	return append([]*Span(nil), e.spans...)
+}
+
+// Trace 获取链路的全部跨度
+func (e *MemoryExporter) Trace(traceId string) []*Span {
+	e.Lock()
+	defer e.Unlock()
+
+	var spans []*Span
+	for _, s := range e.spans {
+		if s.TraceId == traceId {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// Reset 清空跨度
+func (e *MemoryExporter) Reset() {
+	e.Lock()
+	defer e.Unlock()
+
+	e.spans = nil
+}
+
+func NewMemoryExporter() *MemoryExporter {
+	return new(MemoryExporter)
+}
@@ -0,0 +1,210 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/cbwfree/micro-game/utils/errors"
+	"github.com/cbwfree/micro-game/utils/log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	DefaultOTLPBatchSize     = 512             // 默认批量导出数量
+	DefaultOTLPFlushInterval = 5 * time.Second // 默认导出间隔
+	DefaultOTLPQueueSize     = 8192            // 默认队列长度 (超出时丢弃跨度)
+)
+
+// OTLPExporter OpenTelemetry OTLP/HTTP (JSON) 导出器
+// 	endpoint 如 http://127.0.0.1:4318/v1/traces
+type OTLPExporter struct {
+	sync.Mutex
+	endpoint string
+	service  string
+	client   *http.Client
+	spans    []*Span
+	dropped  int
+	flush    chan struct{}
+	exit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func (e *OTLPExporter) Export(span *Span) {
+	e.Lock()
+	defer e.Unlock()
+
+	if len(e.spans) >= DefaultOTLPQueueSize {
+		e.dropped++
+		return
+	}
+
+	e.spans = append(e.spans, span)
+	if len(e.spans) >= DefaultOTLPBatchSize {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush 导出队列中的全部跨度
+func (e *OTLPExporter) Flush() error {
+	e.Lock()
+	spans, dropped := e.spans, e.dropped
+	e.spans, e.dropped = nil, 0
+	e.Unlock()
+
+	if dropped > 0 {
+		log.Warn("[Trace] otlp exporter queue is full, dropped %d spans", dropped)
+	}
+
+	for len(spans) > 0 {
+		n := len(spans)
+		if n > DefaultOTLPBatchSize {
+			n = DefaultOTLPBatchSize
+		}
+		if err := e.send(spans[:n]); err != nil {
+			return err
+		}
+		spans = spans[n:]
+	}
+
+	return nil
+}
+
+func (e *OTLPExporter) Close() error {
+	select {
+	case <-e.exit:
+		return nil
+	default:
+		close(e.exit)
+	}
+	e.wg.Wait()
+	return e.Flush()
+}
+
+func (e *OTLPExporter) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(DefaultOTLPFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.flush:
+		case <-e.exit:
+			return
+		}
+		if err := e.Flush(); err != nil {
+			log.Warn("[Trace] otlp export error: %s", err)
+		}
+	}
+}
+
+func (e *OTLPExporter) send(spans []*Span) error {
+	b, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+
+	rsp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return errors.Unavailable("otlp export: %s", err)
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode/100 != 2 {
+		return errors.Unavailable("otlp export: %s", rsp.Status)
+	}
+
+	return nil
+}
+
+// OTLP JSON 编码
+type otlpAttr struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string     `json:"traceId"`
+	SpanId            string     `json:"spanId"`
+	ParentSpanId      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              Kind       `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+	Status            otlpStatus `json:"status"`
+}
+
+func newOTLPAttr(key, val string) otlpAttr {
+	a := otlpAttr{Key: key}
+	a.Value.StringValue = val
+	return a
+}
+
+func (e *OTLPExporter) encode(spans []*Span) interface{} {
+	var list = make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		s.Lock()
+		os := otlpSpan{
+			TraceId:           s.TraceId,
+			SpanId:            s.SpanId,
+			ParentSpanId:      s.ParentId,
+			Name:              s.Name,
+			Kind:              s.Kind,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: 1},
+		}
+		for k, v := range s.Attrs {
+			os.Attributes = append(os.Attributes, newOTLPAttr(k, v))
+		}
+		if s.Error != "" {
+			os.Status = otlpStatus{Code: 2, Message: s.Error}
+		}
+		s.Unlock()
+		list = append(list, os)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []otlpAttr{newOTLPAttr("service.name", e.service)},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": "micro-game"},
+						"spans": list,
+					},
+				},
+			},
+		},
+	}
+}
+
+// NewOTLPExporter 创建OTLP导出器 (后台定时批量导出)
+func NewOTLPExporter(endpoint string, service string) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		flush:    make(chan struct{}, 1),
+		exit:     make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
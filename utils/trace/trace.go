@@ -0,0 +1,181 @@
+// 分布式链路跟踪
+//
+// 链路ID及父级跨度ID通过 micro metadata 在服务间传递 (RPC调用及消息发布时自动携带),
+// 跨度数据由 Exporter 导出, 未设置导出器时仅传递链路ID, 不记录跨度.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/micro/go-micro/v2/metadata"
+	"sync"
+	"time"
+)
+
+const (
+	MetaTraceId = "Trace-Id" // 链路ID
+	MetaSpanId  = "Span-Id"  // 父级跨度ID
+)
+
+// 跨度类型 (与 OpenTelemetry SpanKind 取值一致)
+type Kind int
+
+const (
+	KindInternal Kind = iota + 1 // 内部调用
+	KindServer                   // RPC服务端
+	KindClient                   // RPC客户端
+	KindProducer                 // 消息发布
+	KindConsumer                 // 消息订阅
+)
+
+var (
+	exporter Exporter
+	mu       sync.RWMutex
+)
+
+// SetExporter 设置跨度导出器 (为nil时关闭跨度记录)
+func SetExporter(e Exporter) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	exporter = e
+}
+
+// GetExporter 获取跨度导出器
+func GetExporter() Exporter {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return exporter
+}
+
+// 跨度
+type Span struct {
+	sync.Mutex
+	TraceId  string            // 链路ID (32位十六进制)
+	SpanId   string            // 跨度ID (16位十六进制)
+	ParentId string            // 父级跨度ID
+	Name     string            // 跨度名称
+	Kind     Kind              // 跨度类型
+	Start    time.Time         // 开始时间
+	End      time.Time         // 结束时间
+	Attrs    map[string]string // 属性
+	Error    string            // 错误信息
+
+	exporter Exporter
+}
+
+// SetAttr 设置属性
+func (s *Span) SetAttr(key string, val interface{}) {
+	if s == nil || s.exporter == nil {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.Attrs[key] = fmt.Sprint(val)
+}
+
+// Finish 结束跨度并导出
+func (s *Span) Finish(err error) {
+	if s == nil || s.exporter == nil {
+		return
+	}
+
+	s.Lock()
+	if !s.End.IsZero() {
+		s.Unlock()
+		return
+	}
+	s.End = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+	s.Unlock()
+
+	s.exporter.Export(s)
+}
+
+// Recording 是否记录跨度
+func (s *Span) Recording() bool {
+	return s != nil && s.exporter != nil
+}
+
+type spanKey struct{}
+
+// Start 开始跨度
+// 	父级跨度优先取上下文中的跨度, 其次取 metadata 中的链路ID及跨度ID, 都不存在时生成新的链路ID
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	s := &Span{
+		Name:     name,
+		Kind:     kind,
+		Start:    time.Now(),
+		SpanId:   NewSpanId(),
+		exporter: GetExporter(),
+	}
+	if s.exporter != nil {
+		s.Attrs = make(map[string]string)
+	}
+
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		s.TraceId, s.ParentId = parent.TraceId, parent.SpanId
+	} else {
+		s.TraceId, s.ParentId = FromContext(ctx)
+	}
+	if s.TraceId == "" {
+		s.TraceId = NewTraceId()
+	}
+
+	ctx = context.WithValue(ctx, spanKey{}, s)
+	ctx = metadata.Set(ctx, MetaTraceId, s.TraceId)
+	ctx = metadata.Set(ctx, MetaSpanId, s.SpanId)
+
+	return ctx, s
+}
+
+// SpanFromContext 获取上下文中的跨度
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// FromContext 获取 metadata 中的链路ID及跨度ID
+func FromContext(ctx context.Context) (traceId string, spanId string) {
+	if s := SpanFromContext(ctx); s != nil {
+		return s.TraceId, s.SpanId
+	}
+	traceId, _ = metadata.Get(ctx, MetaTraceId)
+	spanId, _ = metadata.Get(ctx, MetaSpanId)
+	return
+}
+
+// TraceId 获取上下文中的链路ID
+func TraceId(ctx context.Context) string {
+	traceId, _ := FromContext(ctx)
+	return traceId
+}
+
+// NewContext 设置 metadata 中的链路ID (开始新的链路)
+func NewContext(ctx context.Context, traceId string) context.Context {
+	ctx = metadata.Set(ctx, MetaTraceId, traceId)
+	return metadata.Delete(ctx, MetaSpanId)
+}
+
+// NewTraceId 生成链路ID
+func NewTraceId() string {
+	return randomHex(16)
+}
+
+// NewSpanId 生成跨度ID
+func NewSpanId() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
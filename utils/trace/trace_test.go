@@ -0,0 +1,89 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro/go-micro/v2/metadata"
+)
+
+func TestStart(t *testing.T) {
+	mem := NewMemoryExporter()
+	SetExporter(mem)
+	defer SetExporter(nil)
+
+	ctx, root := Start(context.Background(), "root", KindServer)
+	if len(root.TraceId) != 32 || len(root.SpanId) != 16 || root.ParentId != "" {
+		t.Fatalf("invalid root span: %+v", root)
+	}
+
+	// 同进程内使用上下文中的跨度
+	_, child := Start(ctx, "child", KindInternal)
+	if child.TraceId != root.TraceId || child.ParentId != root.SpanId {
+		t.Fatalf("invalid child span: %+v", child)
+	}
+
+	// 跨服务时通过 metadata 传递 (模拟RPC)
+	md, _ := metadata.FromContext(ctx)
+	_, remote := Start(metadata.NewContext(context.Background(), md), "remote", KindServer)
+	if remote.TraceId != root.TraceId || remote.ParentId != root.SpanId {
+		t.Fatalf("invalid remote span: %+v", remote)
+	}
+
+	remote.Finish(nil)
+	child.Finish(errors.New("failed"))
+	child.Finish(nil)
+	root.Finish(nil)
+
+	spans := mem.Trace(root.TraceId)
+	if len(spans) != 3 || spans[1].Error != "failed" {
+		t.Fatalf("unexpected spans: %d", len(spans))
+	}
+}
+
+func TestNewContext(t *testing.T) {
+	SetExporter(nil)
+
+	ctx := NewContext(context.Background(), "0123456789abcdef0123456789abcdef")
+	_, span := Start(ctx, "request", KindServer)
+	if span.TraceId != "0123456789abcdef0123456789abcdef" || span.ParentId != "" {
+		t.Fatalf("invalid span: %+v", span)
+	}
+	if span.Recording() {
+		t.Fatal("span should not be recorded without exporter")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &body); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	e := NewOTLPExporter(srv.URL, "test")
+	SetExporter(e)
+	_, span := Start(context.Background(), "otlp", KindServer)
+	span.SetAttr("cmd", 10001)
+	span.Finish(nil)
+	SetExporter(nil)
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rs := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	ss := rs["scopeSpans"].([]interface{})[0].(map[string]interface{})
+	s := ss["spans"].([]interface{})[0].(map[string]interface{})
+	if s["traceId"] != span.TraceId || s["name"] != "otlp" {
+		t.Fatalf("unexpected span: %v", s)
+	}
+}
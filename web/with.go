@@ -1,6 +1,8 @@
 package web
 
 import (
+	"github.com/cbwfree/micro-game/utils/health"
+	"github.com/cbwfree/micro-game/utils/metrics"
 	"github.com/cbwfree/micro-game/utils/tool"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
@@ -106,3 +108,44 @@ func WithSession(store string, secret string) ServerWith {
 		))
 	}
 }
+
+// 协议文档导出 (protocol.Router 实现此接口)
+type ProtocolSchema interface {
+	SchemaJSON() ([]byte, error)
+}
+
+// WithProtocol 绑定协议文档, 供客户端查看协议或生成代码
+func WithProtocol(prefix string, schema ProtocolSchema) ServerWith {
+	return func(s *Server) {
+		s.echo.GET(prefix, func(ctx echo.Context) error {
+			b, err := schema.SchemaJSON()
+			if err != nil {
+				return err
+			}
+			return ctx.JSONBlob(http.StatusOK, b)
+		})
+	}
+}
+
+// WithMetrics 绑定监控指标导出 (Prometheus)
+func WithMetrics(prefix string) ServerWith {
+	return func(s *Server) {
+		s.echo.GET(prefix, echo.WrapHandler(metrics.Handler()))
+	}
+}
+
+// WithHealth 绑定健康检查 (prefix/live 存活检查, prefix/ready 就绪检查, 未就绪时返回503)
+func WithHealth(prefix string) ServerWith {
+	return func(s *Server) {
+		s.echo.GET(path.Join(prefix, "live"), func(ctx echo.Context) error {
+			return ctx.JSON(http.StatusOK, map[string]string{"status": health.StatusUp})
+		})
+		s.echo.GET(path.Join(prefix, "ready"), func(ctx echo.Context) error {
+			r := health.Check(ctx.Request().Context())
+			if !r.Up() {
+				return ctx.JSON(http.StatusServiceUnavailable, r)
+			}
+			return ctx.JSON(http.StatusOK, r)
+		})
+	}
+}